@@ -1,21 +1,29 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"gobloks/internal/config"
 	"gobloks/internal/manager"
 	"gobloks/internal/server"
 	"gobloks/internal/types"
+	"log"
+	"os"
 )
 
 func main() {
-	isProd := flag.Bool("production", false, "true if running in production")
-	flag.Parse()
-	server.Start(8888, *isProd)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	server.Start(cfg)
 
-	globalGameManager := manager.InitGameManager()
+	globalGameManager := manager.InitGameManager(nil, manager.Limits{})
 
-	gid := globalGameManager.CreateGame(types.GameConfig{
+	gid, err := globalGameManager.CreateGame(types.GameConfig{
 		Players:     1,
 		BlockDegree: 5,
 		Density:     1,
@@ -24,18 +32,22 @@ func main() {
 		TimeBonus:   0,
 		Hints:       0,
 	})
+	if err != nil {
+		fmt.Printf("error creating game: %s\n", err)
+		return
+	}
 	gs, err := globalGameManager.FindGame(gid)
 	if err != nil {
 		fmt.Printf("error finding game: %s\n", err)
 		return
 	}
-	pid1, err := gs.AddPlayer("p1", 0xff00ff)
+	pid1, err := gs.AddPlayer("p1", 0xff00ff, 0)
 	if err != nil {
 		fmt.Printf("error connecting player: %s\n", err)
 		return
 	}
 	fmt.Println(pid1)
-	// pid2, err := gs.AddPlayer("p2", 0xffff00)
+	// pid2, err := gs.AddPlayer("p2", 0xffff00, 0)
 	// if err != nil {
 	// 	fmt.Printf("error connecting player: %s\n", err)
 	// 	return
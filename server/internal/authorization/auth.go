@@ -5,101 +5,148 @@ import (
 	"encoding/base64"
 	"errors"
 	"gobloks/internal/types"
-	"log"
 	"net/http"
-	"os"
-	"reflect"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
-const KeyLocation = "key.priv"
 const AccessTokenHeader = "Access-Token"
 const AccessTokenQuery = "access_token"
 
-var secretKey []byte
+// How long a token lasts, in seconds. Long games refresh before it runs out.
+const AccessTokenTTL = 3600
 
-func AuthMiddleware(noAuth []gin.HandlerFunc) gin.HandlerFunc {
+const claimsKey = "claims"
+
+// What a gobloks token says about its holder
+type Claims struct {
+	PlayerID   types.PlayerID `json:"PlayerId"`
+	GameID     types.GameID   `json:"GameId"`
+	Spectator  bool           `json:"Spectator,omitempty"`
+	Generation uint           `json:"Generation,omitempty"` // of the seat, see Seats
+	jwt.StandardClaims
+}
+
+// Check the standard claims, which the parser only checks when they're present
+func (claims *Claims) Valid() error {
+	now := time.Now().Unix()
+	if !claims.VerifyExpiresAt(now, true) || !claims.VerifyIssuedAt(now, true) {
+		return errors.New("token expired")
+	}
+	if claims.Id == "" || claims.GameID == "" {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (claims *Claims) Expires() time.Time {
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// Require a valid token, from the header or else the query string. A request
+// naming a game must name the one the token is for.
+func Authenticate(seats Seats) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		for _, handler := range noAuth {
-			// go can't compare function pointers??
-			if reflect.ValueOf(c.Handler()).Pointer() == reflect.ValueOf(handler).Pointer() {
-				c.Next()
-				return
-			}
+		token := c.GetHeader(AccessTokenHeader)
+		if token == "" {
+			token = c.Query(AccessTokenQuery)
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "missing access token"})
+			return
 		}
 
-		token, err := verifyAccessToken(c.GetHeader(AccessTokenHeader))
-		if err != nil {
-			query_tok, ok := c.GetQuery(AccessTokenQuery)
-			if !ok {
-				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "access denied"})
-				return
-			}
-			token, err = verifyAccessToken(query_tok)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "access denied"})
-				return
+		claims, err := verifyAccessToken(token)
+		if err == nil && !claims.Spectator {
+			var generation uint
+			generation, err = seats.TokenGeneration(claims.GameID, claims.PlayerID)
+			if err == nil && generation != claims.Generation {
+				err = errors.New("token revoked")
 			}
 		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "access denied"})
+			return
+		}
+		if gid, ok := c.GetQuery("game"); ok && types.GameID(gid) != claims.GameID {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "token is for another game"})
+			return
+		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("pid", types.PlayerID(claims["PlayerId"].(float64)))
-		c.Set("gid", types.GameID(claims["GameId"].(string)))
+		c.Set(claimsKey, claims)
+		c.Set("pid", claims.PlayerID)
+		c.Set("gid", claims.GameID)
 		c.Next()
 	}
 }
 
-func SetupKeys() {
-	if _, err := os.ReadFile(KeyLocation); err != nil {
-		file, err := os.OpenFile(KeyLocation, os.O_WRONLY|os.O_CREATE, 0644)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer file.Close()
-
-		key, err := generateKey()
-		if err != nil {
-			log.Fatal(err)
-		}
+// The claims of the token that authenticated the request
+func GetClaims(c *gin.Context) *Claims {
+	return c.MustGet(claimsKey).(*Claims)
+}
 
-		_, err = file.WriteString(key)
-		if err != nil {
-			log.Fatal(err)
+// Reject spectator tokens on routes that act on behalf of a player
+func PlayerOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetClaims(c).Spectator {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "spectators can't do that"})
+			return
 		}
+		c.Next()
 	}
+}
 
-	secretKey, _ = os.ReadFile(KeyLocation)
+// Token for a seat, valid while the seat stays at the given token generation
+func CreateAccessToken(pid types.PlayerID, gid types.GameID, generation uint, ttl int) (string, error) {
+	return createToken(pid, gid, generation, false, ttl)
 }
 
-func CreateAccessToken(pid types.PlayerID, gid types.GameID, ttl int) (string, error) {
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.MapClaims{
-			"PlayerId":  pid,
-			"GameId":    gid,
-			"ExpiresAt": time.Now().Add(time.Duration(ttl) * time.Second),
-		},
-	)
+// Token for watching a game. Spectators have no seat, so the player id is always 0.
+func CreateSpectatorToken(gid types.GameID, ttl int) (string, error) {
+	return createToken(0, gid, 0, true, ttl)
+}
 
-	tokenString, err := token.SignedString(secretKey)
+func createToken(pid types.PlayerID, gid types.GameID, generation uint, spectator bool, ttl int) (string, error) {
+	jti, err := generateKey()
+	if err != nil {
+		return "", err
+	}
 
+	now := time.Now()
+	expires := now.Add(time.Duration(ttl) * time.Second)
+	claims := &Claims{
+		PlayerID:   pid,
+		GameID:     gid,
+		Spectator:  spectator,
+		Generation: generation,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expires.Unix(),
+		},
+	}
+
+	key, err := currentKey()
 	if err != nil {
 		return "", err
 	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
 
-	return tokenString, nil
+	return token.SignedString(key.private)
 }
 
-func verifyAccessToken(token string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid token")
-		}
-		return secretKey, nil
-	})
+func verifyAccessToken(token string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, claims, verificationKey); err != nil {
+		return nil, err
+	}
+	if isRevoked(claims.GameID, claims.Id) {
+		return nil, errors.New("token revoked")
+	}
+	return claims, nil
 }
 
 func generateKey() (string, error) {
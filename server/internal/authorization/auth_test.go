@@ -0,0 +1,145 @@
+package authorization
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"gobloks/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Token generations of the seats in game GAME
+type testSeats map[types.PlayerID]uint
+
+func (seats testSeats) TokenGeneration(gid types.GameID, pid types.PlayerID) (uint, error) {
+	generation, ok := seats[pid]
+	if gid != "GAME" || !ok {
+		return 0, errors.New("invalid player id")
+	}
+	return generation, nil
+}
+
+func testRouter(seats Seats) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/game", Authenticate(seats), ok)
+	router.GET("/player", Authenticate(seats), PlayerOnly(), ok)
+	return router
+}
+
+// Sign claims with a key of our choosing, under the given kid
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// Rewrite the payload without re-signing
+func tamper(t *testing.T, token string, from, to string) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), from, to, 1)))
+	return strings.Join(parts, ".")
+}
+
+func TestAuthenticate(t *testing.T) {
+	useTestKeys(t, ALG_EDDSA)
+	key, _ := currentKey()
+	router := testRouter(testSeats{1: 0, 2: 1})
+
+	valid, _ := CreateAccessToken(1, "GAME", 0, 60)
+	spectator, _ := CreateSpectatorToken("GAME", 60)
+	expired, _ := CreateAccessToken(1, "GAME", 0, -60)
+	revoked, _ := CreateAccessToken(2, "GAME", 0, 60) // seat 2 has moved on a generation
+	current, _ := CreateAccessToken(2, "GAME", 1, 60)
+	unseated, _ := CreateAccessToken(3, "GAME", 0, 60)
+
+	now := time.Now().Unix()
+	standard := jwt.StandardClaims{Id: "forged", IssuedAt: now, ExpiresAt: now + 60}
+	public := []byte(key.public.(ed25519.PublicKey))
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		query  string
+		status int
+	}{
+		{"valid", "/game", valid, "", http.StatusOK},
+		{"valid in query", "/game", "", valid, http.StatusOK},
+		{"matching game", "/game?game=GAME", valid, "", http.StatusOK},
+		{"spectator", "/game", spectator, "", http.StatusOK},
+		{"missing", "/game", "", "", http.StatusUnauthorized},
+		{"garbage", "/game", "not a token", "", http.StatusUnauthorized},
+		{"expired", "/game", expired, "", http.StatusUnauthorized},
+		{"revoked", "/game", revoked, "", http.StatusUnauthorized},
+		{"current generation", "/game", current, "", http.StatusOK},
+		{"no such seat", "/game", unseated, "", http.StatusUnauthorized},
+		{"tampered player", "/game", tamper(t, valid, `"PlayerId":1`, `"PlayerId":3`), "", http.StatusUnauthorized},
+		{"tampered game", "/game", tamper(t, valid, `"GameId":"GAME"`, `"GameId":"EMAG"`), "", http.StatusUnauthorized},
+		{"wrong game", "/game?game=OTHER", valid, "", http.StatusForbidden},
+		{"spectator acting as player", "/player", spectator, "", http.StatusForbidden},
+		{"player acting as player", "/player", valid, "", http.StatusOK},
+		{
+			"unsigned", "/game",
+			signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, key.id, &Claims{PlayerID: 1, GameID: "GAME", StandardClaims: standard}),
+			"", http.StatusUnauthorized,
+		},
+		{
+			// the public key used as an HMAC secret
+			"wrong algorithm", "/game",
+			signToken(t, jwt.SigningMethodHS256, public, key.id, &Claims{PlayerID: 1, GameID: "GAME", StandardClaims: standard}),
+			"", http.StatusUnauthorized,
+		},
+		{
+			"unknown key", "/game",
+			signToken(t, jwt.SigningMethodEdDSA, key.private, "retired", &Claims{PlayerID: 1, GameID: "GAME", StandardClaims: standard}),
+			"", http.StatusUnauthorized,
+		},
+		{
+			"no expiry", "/game",
+			signToken(t, jwt.SigningMethodEdDSA, key.private, key.id, &Claims{PlayerID: 1, GameID: "GAME", StandardClaims: jwt.StandardClaims{Id: "x", IssuedAt: now}}),
+			"", http.StatusUnauthorized,
+		},
+		{
+			"malformed claims", "/game",
+			signToken(t, jwt.SigningMethodEdDSA, key.private, key.id, jwt.MapClaims{"PlayerId": "one", "GameId": 7, "jti": "x", "iat": now, "exp": now + 60}),
+			"", http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tc.path
+			if tc.query != "" {
+				path += "?" + AccessTokenQuery + "=" + tc.query
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tc.header != "" {
+				req.Header.Set(AccessTokenHeader, tc.header)
+			}
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			if res.Code != tc.status {
+				t.Errorf("expected %d, got %d: %s", tc.status, res.Code, res.Body)
+			}
+		})
+	}
+}
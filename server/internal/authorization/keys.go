@@ -0,0 +1,286 @@
+package authorization
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Algorithms tokens can be signed with. Only gobloks can verify HS256 tokens,
+// the others can be checked by anyone with the public keys.
+const (
+	ALG_HS256 = "HS256"
+	ALG_EDDSA = "EdDSA"
+	ALG_RS256 = "RS256"
+)
+
+const KeyDir = "keys"
+const KeyExtension = ".key"
+
+// Key material in the environment overrides the key directory
+const (
+	KeyEnv   = "GOBLOKS_SIGNING_KEY"
+	KeyIDEnv = "GOBLOKS_SIGNING_KEY_ID"
+)
+
+type KeyOptions struct {
+	Dir       string // one file per key, named <kid>.key. The name that sorts last signs.
+	Algorithm string // for keys generated here
+	Rotate    bool   // start signing with a new key, keeping the old ones for verification
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{} // verifies the key's signatures, the secret itself for HMAC
+}
+
+var keyring = struct {
+	lock    sync.RWMutex
+	signing *signingKey
+	byID    map[string]*signingKey
+}{
+	byID: make(map[string]*signingKey),
+}
+
+// Load the signing keys, generating one if there are none yet
+func SetupKeys(opts KeyOptions) error {
+	if material := os.Getenv(KeyEnv); material != "" {
+		id := os.Getenv(KeyIDEnv)
+		if id == "" {
+			id = "env"
+		}
+		key, err := parseKey(id, []byte(material))
+		if err != nil {
+			return fmt.Errorf("%s: %w", KeyEnv, err)
+		}
+		installKeys([]*signingKey{key})
+		return nil
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = KeyDir
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	keys, err := loadKeys(dir)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 || opts.Rotate {
+		key, err := generateSigningKey(dir, opts.Algorithm)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	installKeys(keys)
+	return nil
+}
+
+// The last key signs, and every key verifies
+func installKeys(keys []*signingKey) {
+	keyring.lock.Lock()
+	defer keyring.lock.Unlock()
+
+	keyring.byID = make(map[string]*signingKey, len(keys))
+	for _, key := range keys {
+		keyring.byID[key.id] = key
+	}
+	keyring.signing = keys[len(keys)-1]
+}
+
+func currentKey() (*signingKey, error) {
+	keyring.lock.RLock()
+	defer keyring.lock.RUnlock()
+	if keyring.signing == nil {
+		return nil, errors.New("no signing key loaded")
+	}
+	return keyring.signing, nil
+}
+
+// The verification key for a token, checking it was signed the way that key signs
+func verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	keyring.lock.RLock()
+	key, ok := keyring.byID[kid]
+	keyring.lock.RUnlock()
+
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid token")
+	}
+	return key.public, nil
+}
+
+func loadKeys(dir string) ([]*signingKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*signingKey, 0, len(entries))
+	for _, entry := range entries { // sorted by name
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, KeyExtension) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(strings.TrimSuffix(name, KeyExtension), data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Keys are either a PEM encoded private key, or an HMAC secret
+func parseKey(id string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		secret := bytes.TrimSpace(data)
+		if len(secret) < 32 {
+			return nil, errors.New("HMAC secret must be at least 32 bytes")
+		}
+		return &signingKey{id, jwt.SigningMethodHS256, secret, secret}, nil
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		return &signingKey{id, jwt.SigningMethodEdDSA, private, private.Public()}, nil
+	case *rsa.PrivateKey:
+		return &signingKey{id, jwt.SigningMethodRS256, private, &private.PublicKey}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", private)
+}
+
+// Make a new key and save it, readable only by the server
+func generateSigningKey(dir string, algorithm string) (*signingKey, error) {
+	var data []byte
+	switch algorithm {
+	case "", ALG_HS256:
+		secret, err := generateKey()
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(secret)
+	case ALG_EDDSA, ALG_RS256:
+		var private interface{}
+		var err error
+		if algorithm == ALG_EDDSA {
+			_, private, err = ed25519.GenerateKey(rand.Reader)
+		} else {
+			private, err = rsa.GenerateKey(rand.Reader, 2048)
+		}
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	default:
+		return nil, fmt.Errorf("unknown signing algorithm %q", algorithm)
+	}
+
+	suffix, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	// ids start with the time, so a newer key sorts after the ones it replaces
+	id := time.Now().UTC().Format("20060102T150405.000000000") + "-" + suffix[:6]
+
+	file, err := os.OpenFile(filepath.Join(dir, id+KeyExtension), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return nil, err
+	}
+
+	return parseKey(id, data)
+}
+
+// A public key in JWK form
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// The public halves of the asymmetric keys, so other services can verify tokens.
+// HMAC secrets are never published.
+func PublicKeys() *KeySet {
+	keyring.lock.RLock()
+	defer keyring.lock.RUnlock()
+
+	set := &KeySet{Keys: make([]JSONWebKey, 0, len(keyring.byID))}
+	for _, key := range keyring.byID {
+		jwk := JSONWebKey{Kid: key.id, Alg: key.method.Alg(), Use: "sig"}
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	slices.SortFunc(set.Keys, func(a, b JSONWebKey) int { return strings.Compare(a.Kid, b.Kid) })
+	return set
+}
@@ -0,0 +1,131 @@
+package authorization
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Sign with a fresh key of the given algorithm for the rest of the test
+func useTestKeys(t *testing.T, algorithm string) string {
+	t.Helper()
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	if err := SetupKeys(KeyOptions{Dir: dir, Algorithm: algorithm}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func tokenHeader(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header
+}
+
+func TestSigningAlgorithms(t *testing.T) {
+	for _, alg := range []string{ALG_HS256, ALG_EDDSA, ALG_RS256} {
+		dir := useTestKeys(t, alg)
+
+		token, err := CreateAccessToken(1, "ALGS", 0, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifyAccessToken(token); err != nil {
+			t.Errorf("%s: token rejected: %v", alg, err)
+			continue
+		}
+		if header := tokenHeader(t, token); header["alg"] != alg {
+			t.Errorf("expected a %s token, got %v", alg, header["alg"])
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+KeyExtension))
+		if len(files) != 1 {
+			t.Fatalf("%s: expected one key file, got %v", alg, files)
+		}
+		info, _ := os.Stat(files[0])
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s: key file has mode %v", alg, info.Mode().Perm())
+		}
+
+		published := len(PublicKeys().Keys)
+		if alg == ALG_HS256 && published != 0 {
+			t.Error("HMAC secret was published")
+		} else if alg != ALG_HS256 && published != 1 {
+			t.Errorf("%s: expected one public key, got %d", alg, published)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := useTestKeys(t, ALG_EDDSA)
+	before, _ := CreateAccessToken(1, "ROTA", 0, 60)
+
+	if err := SetupKeys(KeyOptions{Dir: dir, Algorithm: ALG_HS256, Rotate: true}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := CreateAccessToken(1, "ROTA", 0, 60)
+
+	if _, err := verifyAccessToken(before); err != nil {
+		t.Errorf("token from the old key rejected after rotation: %v", err)
+	}
+	if _, err := verifyAccessToken(after); err != nil {
+		t.Fatal(err)
+	}
+	if header := tokenHeader(t, after); header["alg"] != ALG_HS256 {
+		t.Errorf("expected the new key to sign, got %v", header["alg"])
+	}
+
+	// retiring the old key stops its tokens working
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+KeyExtension))
+	os.Remove(files[0])
+	if err := SetupKeys(KeyOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyAccessToken(before); err == nil {
+		t.Error("token from a retired key accepted")
+	}
+}
+
+func TestKeyFromEnvironment(t *testing.T) {
+	t.Setenv(KeyEnv, "a shared secret of at least thirty two bytes")
+	t.Setenv(KeyIDEnv, "primary")
+	if err := SetupKeys(KeyOptions{Dir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := CreateAccessToken(1, "ENVK", 0, 60)
+	if _, err := verifyAccessToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if header := tokenHeader(t, token); header["kid"] != "primary" {
+		t.Errorf("expected kid primary, got %v", header["kid"])
+	}
+
+	t.Setenv(KeyEnv, "too short")
+	if err := SetupKeys(KeyOptions{}); err == nil {
+		t.Error("short secret accepted")
+	}
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	useTestKeys(t, ALG_HS256)
+	key, _ := currentKey()
+
+	// a token claiming the right kid but signed another way
+	claims := jwt.MapClaims{"PlayerId": 1, "GameId": "MISM", "jti": "x", "iat": 0, "exp": 1 << 40}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyAccessToken(signed); err == nil {
+		t.Error("token signed with the wrong algorithm accepted")
+	}
+}
@@ -0,0 +1,62 @@
+package authorization
+
+import (
+	"gobloks/internal/types"
+	"sync"
+	"time"
+)
+
+// Where Authenticate finds the current token generation of each seat. Player
+// tokens from any other generation have been revoked.
+type Seats interface {
+	TokenGeneration(gid types.GameID, pid types.PlayerID) (uint, error)
+}
+
+// Spectator tokens withdrawn before expiring, by game. Spectators have no seat
+// to keep a generation in, and a token only lets them watch, so these are kept
+// in memory only.
+var revocations = struct {
+	lock  sync.Mutex
+	games map[types.GameID]map[string]time.Time // jti to expiry, after which it can be forgotten
+}{
+	games: make(map[types.GameID]map[string]time.Time),
+}
+
+// Withdraw a single spectator token, such as one that has just been refreshed
+func Revoke(gid types.GameID, jti string, expires time.Time) {
+	revocations.lock.Lock()
+	defer revocations.lock.Unlock()
+
+	revoked, ok := revocations.games[gid]
+	if !ok {
+		revoked = make(map[string]time.Time)
+		revocations.games[gid] = revoked
+	}
+	prune(revoked, time.Now())
+	revoked[jti] = expires
+}
+
+// Drop everything kept for a game once it has been cleaned up
+func ForgetGame(gid types.GameID) {
+	revocations.lock.Lock()
+	defer revocations.lock.Unlock()
+
+	delete(revocations.games, gid)
+}
+
+func isRevoked(gid types.GameID, jti string) bool {
+	revocations.lock.Lock()
+	defer revocations.lock.Unlock()
+
+	_, revoked := revocations.games[gid][jti]
+	return revoked
+}
+
+// Forget tokens that have expired anyway. Must be called with the revocations lock held.
+func prune(revoked map[string]time.Time, now time.Time) {
+	for jti, expires := range revoked {
+		if now.After(expires) {
+			delete(revoked, jti)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package authorization
+
+import (
+	"gobloks/internal/types"
+	"testing"
+	"time"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	useTestKeys(t, ALG_HS256)
+
+	live, err := CreateAccessToken(1, "EXPY", 0, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyAccessToken(live); err != nil {
+		t.Errorf("fresh token rejected: %v", err)
+	}
+
+	expired, err := CreateAccessToken(1, "EXPY", 0, -60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyAccessToken(expired); err == nil {
+		t.Error("expired token accepted")
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	useTestKeys(t, ALG_HS256)
+	gid := types.GameID("RVKE")
+	defer ForgetGame(gid)
+
+	first, _ := CreateSpectatorToken(gid, 60)
+	second, _ := CreateSpectatorToken(gid, 60)
+	player, _ := CreateAccessToken(1, gid, 0, 60)
+
+	claims, err := verifyAccessToken(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Revoke(gid, claims.Id, time.Now().Add(time.Minute))
+	if _, err := verifyAccessToken(first); err == nil {
+		t.Error("revoked token accepted")
+	}
+	if _, err := verifyAccessToken(second); err != nil {
+		t.Errorf("another spectator's token was revoked: %v", err)
+	}
+	if _, err := verifyAccessToken(player); err != nil {
+		t.Errorf("player token was revoked: %v", err)
+	}
+
+	// expired revocations are forgotten
+	Revoke(gid, "stale", time.Now().Add(-time.Minute))
+	Revoke(gid, "fresh", time.Now().Add(time.Minute))
+	if isRevoked(gid, "stale") {
+		t.Error("expired revocation kept")
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gobloks/internal/authorization"
+	"os"
+	"strings"
+	"time"
+)
+
+// Every setting can also be given as an environment variable, named after its
+// flag: -reconnect-grace is GOBLOKS_RECONNECT_GRACE. Flags win over the
+// environment, which wins over the config file.
+const EnvPrefix = "GOBLOKS_"
+
+type Config struct {
+	Listen               string   `json:"listen"`
+	Production           bool     `json:"production"`
+	AllowedOrigins       []string `json:"allowedOrigins"`       // any origin if empty
+	CheckWebsocketOrigin bool     `json:"checkWebsocketOrigin"` // against AllowedOrigins, or the host if there are none
+	StorageDir           string   `json:"storage"`              // empty keeps games in memory only
+	KeyDir               string   `json:"keys"`
+	KeyAlgorithm         string   `json:"keyAlgorithm"`
+	RotateKey            bool     `json:"-"` // a one-off action, so never read from a file
+	ReconnectGrace       Duration `json:"reconnectGrace"`
+	CleanupInterval      Duration `json:"cleanupInterval"`
+	MaxGames             int      `json:"maxGames"`   // 0 for no limit
+	MaxPlayers           uint     `json:"maxPlayers"` // per game, 0 for no limit beyond the game config's own
+}
+
+// A time.Duration written as a string like "15s" in config files
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func Default() *Config {
+	return &Config{
+		Listen:          "0.0.0.0:8888",
+		KeyDir:          authorization.KeyDir,
+		KeyAlgorithm:    authorization.ALG_HS256,
+		ReconnectGrace:  Duration(15 * time.Second),
+		CleanupInterval: Duration(24 * time.Hour),
+	}
+}
+
+// Build the config from the defaults, then the config file, the environment and
+// the command line, in that order
+func Load(args []string) (*Config, error) {
+	// find the config file first, since everything else is layered over it
+	path := os.Getenv(EnvPrefix + "CONFIG")
+	probe := Default().flags(&path)
+	if err := probe.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	flags := cfg.flags(&path)
+	var envErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok && envErr == nil {
+			if err := f.Value.Set(value); err != nil {
+				envErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	})
+	if envErr != nil {
+		return nil, envErr
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	// production always has a list of allowed origins, so websockets are held to it too
+	if cfg.Production {
+		cfg.CheckWebsocketOrigin = true
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Flags that write straight into the config, defaulting to what it already holds
+func (cfg *Config) flags(path *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("gobloks", flag.ContinueOnError)
+	flags.StringVar(path, "config", *path, "JSON config file")
+	flags.StringVar(&cfg.Listen, "listen", cfg.Listen, "address to serve on")
+	flags.BoolVar(&cfg.Production, "production", cfg.Production, "true if running in production")
+	flags.Func("allowed-origins", "comma separated origins allowed to call the API, any if empty", func(value string) error {
+		cfg.AllowedOrigins = splitList(value)
+		return nil
+	})
+	flags.BoolVar(&cfg.CheckWebsocketOrigin, "check-websocket-origin", cfg.CheckWebsocketOrigin, "reject websockets from origins that aren't allowed")
+	flags.StringVar(&cfg.StorageDir, "storage", cfg.StorageDir, "directory to persist games in, empty to disable")
+	flags.StringVar(&cfg.KeyDir, "keys", cfg.KeyDir, "directory holding the token signing keys")
+	flags.StringVar(&cfg.KeyAlgorithm, "key-alg", cfg.KeyAlgorithm, "algorithm for new signing keys: HS256, EdDSA or RS256")
+	flags.BoolVar(&cfg.RotateKey, "rotate-key", cfg.RotateKey, "sign with a new key, keeping the old ones for verification")
+	flags.DurationVar((*time.Duration)(&cfg.ReconnectGrace), "reconnect-grace", time.Duration(cfg.ReconnectGrace), "how long a disconnected player has to come back")
+	flags.DurationVar((*time.Duration)(&cfg.CleanupInterval), "cleanup-interval", time.Duration(cfg.CleanupInterval), "how often stale games are cleaned up")
+	flags.IntVar(&cfg.MaxGames, "max-games", cfg.MaxGames, "most games running at once, 0 for no limit")
+	flags.UintVar(&cfg.MaxPlayers, "max-players", cfg.MaxPlayers, "most players in one game, 0 for no limit")
+	return flags
+}
+
+func (cfg *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.Listen == "" {
+		return errors.New("no listen address")
+	}
+	if cfg.Production && len(cfg.AllowedOrigins) == 0 {
+		return errors.New("production needs a list of allowed origins")
+	}
+	if cfg.ReconnectGrace <= 0 {
+		return errors.New("reconnect grace must be positive")
+	}
+	if cfg.CleanupInterval <= 0 {
+		return errors.New("cleanup interval must be positive")
+	}
+	if cfg.MaxGames < 0 {
+		return errors.New("max games can't be negative")
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
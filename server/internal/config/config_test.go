@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gobloks.json")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"listen": "127.0.0.1:9000",
+		"allowedOrigins": ["https://file.example"],
+		"reconnectGrace": "30s",
+		"maxGames": 10,
+		"maxPlayers": 4
+	}`)
+	t.Setenv(EnvPrefix+"CONFIG", path)
+	t.Setenv(EnvPrefix+"MAX_GAMES", "20")
+	t.Setenv(EnvPrefix+"RECONNECT_GRACE", "45s")
+
+	cfg, err := Load([]string{"-max-games", "30", "-check-websocket-origin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Listen != "127.0.0.1:9000" || cfg.MaxPlayers != 4 {
+		t.Errorf("file settings not applied: %+v", cfg)
+	}
+	if time.Duration(cfg.ReconnectGrace) != 45*time.Second {
+		t.Errorf("environment should override the file, got grace %v", time.Duration(cfg.ReconnectGrace))
+	}
+	if cfg.MaxGames != 30 || !cfg.CheckWebsocketOrigin {
+		t.Errorf("flags should override everything, got %+v", cfg)
+	}
+	if len(cfg.AllowedOrigins) != 1 || cfg.AllowedOrigins[0] != "https://file.example" {
+		t.Errorf("origins from the file lost: %v", cfg.AllowedOrigins)
+	}
+	if time.Duration(cfg.CleanupInterval) != 24*time.Hour || cfg.StorageDir != "" {
+		t.Errorf("defaults lost: %+v", cfg)
+	}
+}
+
+func TestLoadFlagsOnly(t *testing.T) {
+	cfg, err := Load([]string{"-listen", ":7000", "-allowed-origins", "https://a.example, https://b.example", "-production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":7000" || !cfg.Production || len(cfg.AllowedOrigins) != 2 || cfg.AllowedOrigins[1] != "https://b.example" {
+		t.Errorf("flags not applied: %+v", cfg)
+	}
+	if !cfg.CheckWebsocketOrigin {
+		t.Error("production should check websocket origins")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := map[string]struct {
+		file string
+		args []string
+	}{
+		"production without origins": {args: []string{"-production"}},
+		"zero grace":                 {args: []string{"-reconnect-grace", "0s"}},
+		"negative games":             {args: []string{"-max-games", "-1"}},
+		"unknown flag":               {args: []string{"-colour", "red"}},
+		"unknown setting":            {file: `{"colour": "red"}`},
+		"bad duration":               {file: `{"cleanupInterval": "daily"}`},
+		"missing file":               {args: []string{"-config", "/nonexistent/gobloks.json"}},
+	}
+	for name, tc := range cases {
+		args := tc.args
+		if tc.file != "" {
+			args = append(args, "-config", writeConfig(t, tc.file))
+		}
+		if _, err := Load(args); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package game
+
+import (
+	"fmt"
+	"gobloks/internal/types"
+	"time"
+)
+
+// Give up the current turn without placing a piece
+func (g *Game) Pass(pid types.PlayerID) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return err
+	}
+
+	if !g.config.TurnBased {
+		return gameError(ERR_NOT_TURN_BASED, "passing requires turn based play")
+	}
+
+	_, err = g.playerActionValid(player)
+	if err != nil {
+		return err
+	}
+
+	g.lastActive = time.Now()
+	player.playerTimer.Pause()
+	g.recordMove(MOVE_PASS, player, nil)
+	g.updateGameState(player)
+	g.sendGameMessage(fmt.Sprintf("%s passed", player.name))
+	return nil
+}
+
+// Leave play for the rest of the game
+func (g *Game) Resign(pid types.PlayerID) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return err
+	}
+
+	if g.state.status.Has(COMPLETE) {
+		return gameError(ERR_GAME_OVER, "game is over")
+	}
+	if player.state.status.Has(DISABLED) {
+		return gameError(ERR_PLAYER_INACTIVE, "player inactive")
+	}
+	if !g.state.status.Has(FULL) {
+		return gameError(ERR_WAITING_FOR_PLAYERS, "waiting for all players")
+	}
+
+	g.lastActive = time.Now()
+	player.state.status.Set(DISABLED | RESIGNED)
+	player.playerTimer.Pause()
+	if player.connectionTimer != nil {
+		player.connectionTimer.Pause()
+	}
+	g.recordMove(MOVE_RESIGN, player, nil)
+	g.sendGameMessage(fmt.Sprintf("%s resigned", player.name))
+	g.updateGameState(player)
+	return nil
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestPassAndResign(t *testing.T) {
+	g, err := InitGame("PASS", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+
+	expectCode(t, g.Pass(p1), ERR_WAITING_FOR_PLAYERS)
+	expectCode(t, g.Resign(p1), ERR_WAITING_FOR_PLAYERS)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+
+	expectCode(t, g.Pass(p2), ERR_NOT_YOUR_TURN)
+	if err := g.Pass(p1); err != nil {
+		t.Fatal(err)
+	}
+	if g.state.turn != p2 {
+		t.Fatalf("expected turn to pass to %v, got %v", p2, g.state.turn)
+	}
+
+	if err := g.Resign(p2); err != nil {
+		t.Fatal(err)
+	}
+	if !g.players[p2].state.status.Has(DISABLED|RESIGNED) || g.state.turn != p1 {
+		t.Errorf("resigning should disable the player and advance the turn")
+	}
+	expectCode(t, g.Resign(p2), ERR_PLAYER_INACTIVE)
+
+	if err := g.Resign(p1); err != nil {
+		t.Fatal(err)
+	}
+	if !g.state.status.Has(COMPLETE) {
+		t.Errorf("game should end once every player has resigned")
+	}
+	expectCode(t, g.Resign(p1), ERR_GAME_OVER)
+	for pid, player := range g.players {
+		if player.state.status.Has(WINNER | DRAWN) {
+			t.Errorf("player %v resigned but was still given the game", pid)
+		}
+	}
+
+	moves := []types.MoveType{MOVE_PASS, MOVE_RESIGN, MOVE_RESIGN}
+	if len(g.history) != len(moves) {
+		t.Fatalf("expected %v moves, got %v", len(moves), len(g.history))
+	}
+	for ii, move := range g.history {
+		if move.Type != moves[ii] {
+			t.Errorf("move %v: expected type %v, got %v", ii, moves[ii], move.Type)
+		}
+	}
+}
+
+func TestPassRequiresTurns(t *testing.T) {
+	g, err := InitGame("PASS", types.GameConfig{Players: 1, BlockDegree: 4, Density: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := g.AddPlayer("solo", 0xff0000, 0)
+	expectCode(t, g.Pass(pid), ERR_NOT_TURN_BASED)
+}
+
+func TestResignedPlayersCantWin(t *testing.T) {
+	g, err := InitGame("PASS", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+
+	// two has placed everything, but gives up anyway
+	g.players[p2].state.pieces = PieceSet{}
+	if err := g.Resign(p2); err != nil {
+		t.Fatal(err)
+	}
+
+	winners := g.determineWinners()
+	if len(winners) != 1 || winners[0].state.pid != p1 {
+		t.Errorf("expected only %v to win, got %v winners", p1, len(winners))
+	}
+}
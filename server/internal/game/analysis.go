@@ -0,0 +1,171 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"slices"
+)
+
+// Placements the engine weighs when looking for a better move during analysis
+const analysisCandidates = 16
+
+// Games analysed at once. The rest wait for a free slot.
+const maxAnalyses = 2
+
+var analysisSlots = make(chan struct{}, maxAnalyses)
+
+var ErrAnalysisPending = errors.New("analysis in progress")
+
+// The post-game report, available once the game is complete. Games restored
+// without a saved report are analysed when it is first asked for.
+func (g *Game) Analysis() (*types.Analysis, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if !g.state.status.Has(COMPLETE) {
+		return nil, gameError(ERR_GAME_NOT_COMPLETE, "game is not over yet")
+	}
+	if g.analysis == nil {
+		g.startAnalysis()
+		return nil, ErrAnalysisPending
+	}
+	return g.analysis, nil
+}
+
+// Start analysing the finished game in the background, unless it already is.
+// Must be called with the game lock held.
+func (g *Game) startAnalysis() {
+	if g.analyzing || g.analysis != nil {
+		return
+	}
+	g.analyzing = true
+
+	start := g.startingBoard.Copy()
+	history := slices.Clone(g.history)
+	pids := make([]types.PlayerID, 0, len(g.players))
+	for pid, player := range g.players {
+		if player != nil {
+			pids = append(pids, pid)
+		}
+	}
+	slices.Sort(pids)
+	pieces := g.startingPieces.Copy()
+
+	go func() {
+		analysisSlots <- struct{}{}
+		analysis := analyzeGame(start, pieces, pids, history)
+		<-analysisSlots
+
+		g.lock.Lock()
+		defer g.lock.Unlock()
+		g.analysis = analysis
+		g.analyzing = false
+		g.saveSnapshot()
+		fmt.Println("Finished analysing", g.gid)
+	}()
+}
+
+// Replay the history from the starting board, measuring each player's options along the way
+func analyzeGame(
+	board *Board,
+	startingPieces PieceSet,
+	pids []types.PlayerID,
+	history []types.Move,
+) *types.Analysis {
+
+	engine := InitEvalEngine(1)
+	players := make(map[types.PlayerID]*PlayerState, len(pids))
+	for _, pid := range pids {
+		players[pid] = &PlayerState{pid: pid, status: JOINED, pieces: startingPieces.Copy()}
+	}
+
+	mobility := func() map[types.PlayerID]int {
+		counts := make(map[types.PlayerID]int, len(pids))
+		for _, pid := range pids {
+			counts[pid] = len(board.findPlacements(pid, players[pid].pieces))
+		}
+		return counts
+	}
+
+	before := mobility()
+	timeline := []map[types.PlayerID]int{before}
+	turns := make([]types.TurnAnalysis, 0, len(history))
+
+	for _, move := range history {
+		switch move.Type {
+		case MOVE_PLACE:
+			placement := utilities.NewSet(move.Placement)
+			turn := types.TurnAnalysis{Seq: move.Seq, PID: move.PID, Placement: move.Placement}
+
+			candidates := board.findPlacements(move.PID, players[move.PID].pieces)
+			turn.Options = len(candidates)
+			state := &EvalState{&GameState{board.Copy(), move.PID, IN_PROGRESS}, copyPlayers(players)}
+			best, _, err := engine.BestPlacement(state, largestPlacements(candidates, analysisCandidates))
+			if err == nil {
+				turn.Best = best.ToSlice()
+				slices.SortFunc(turn.Best, comparePoints)
+			}
+
+			board.Place(placement, move.PID)
+			pieces := players[move.PID].pieces
+			pieces.Remove(PieceFromPoints(placement))
+
+			after := mobility()
+			for _, pid := range pids {
+				if lost := before[pid] - after[pid]; pid != move.PID && lost > 0 {
+					if turn.Cutoff == nil {
+						turn.Cutoff = make(map[types.PlayerID]int)
+					}
+					turn.Cutoff[pid] = lost
+				}
+			}
+			turns = append(turns, turn)
+			timeline = append(timeline, after)
+			before = after
+
+		case MOVE_TAKEBACK:
+			placement := utilities.NewSet(move.Placement)
+			board.Remove(placement)
+			pieces := players[move.PID].pieces
+			pieces.Add(PieceFromPoints(placement))
+
+			// the taken back turn never happened, as far as the report goes
+			if len(turns) > 0 {
+				turns = turns[:len(turns)-1]
+				timeline = timeline[:len(timeline)-1]
+			}
+			before = timeline[len(timeline)-1]
+		}
+	}
+
+	analysis := &types.Analysis{Players: make([]types.PlayerAnalysis, 0, len(pids)), Turns: turns}
+	for _, pid := range pids {
+		territory := board.findTerritory(types.Owner(pid))
+		pa := types.PlayerAnalysis{
+			PID:       pid,
+			Mobility:  make([]int, len(timeline)),
+			Territory: len(territory),
+			Corners:   len(board.playableCorners(pid, territory)),
+		}
+		for ii, counts := range timeline {
+			pa.Mobility[ii] = counts[pid]
+		}
+		for _, turn := range turns {
+			if lost := turn.Cutoff[pid]; lost > 0 && (pa.WorstCutoff == nil || lost > pa.WorstCutoff.Lost) {
+				pa.WorstCutoff = &types.Cutoff{Seq: turn.Seq, By: turn.PID, Lost: lost}
+			}
+		}
+		analysis.Players = append(analysis.Players, pa)
+	}
+	return analysis
+}
+
+func copyPlayers(players map[types.PlayerID]*PlayerState) map[types.PlayerID]*PlayerState {
+	cpy := make(map[types.PlayerID]*PlayerState, len(players))
+	for pid, player := range players {
+		cpy[pid] = player.Copy()
+	}
+	return cpy
+}
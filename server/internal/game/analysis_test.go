@@ -0,0 +1,136 @@
+package game
+
+import (
+	"errors"
+	"gobloks/internal/types"
+	"testing"
+	"time"
+)
+
+func TestAnalysisAfterGame(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.AddPlayer("one", 0xffffff, 0)
+	g.AddPlayer("two", 0xffffff, 0)
+
+	_, err = g.Analysis()
+	expectCode(t, err, ERR_GAME_NOT_COMPLETE)
+
+	placed := 0
+	for !g.state.status.Has(COMPLETE) {
+		pid := g.state.turn
+		if err := g.PlacePiece(pid, g.players[pid].possiblePlacements.Any().ToSlice()); err != nil {
+			t.Fatal(err)
+		}
+		placed++
+	}
+
+	analysis := waitForAnalysis(t, g)
+
+	if len(analysis.Turns) != placed {
+		t.Errorf("expected %d turns, got %d", placed, len(analysis.Turns))
+	}
+	for _, turn := range analysis.Turns {
+		if turn.Options == 0 || len(turn.Best) == 0 {
+			t.Errorf("turn %d should have options and a best placement", turn.Seq)
+		}
+	}
+	for _, pa := range analysis.Players {
+		if len(pa.Mobility) != placed+1 || pa.Mobility[placed] != 0 {
+			t.Errorf("player %d: expected mobility to run out after %d turns, got %v", pa.PID, placed, pa.Mobility)
+		}
+		if pa.Territory != len(g.state.board.findTerritory(types.Owner(pa.PID))) {
+			t.Errorf("player %d: territory doesn't match the board", pa.PID)
+		}
+	}
+}
+
+func TestAnalysisRestored(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.AddPlayer("one", 0xffffff, 0)
+	g.AddPlayer("two", 0xffffff, 0)
+	for !g.state.status.Has(COMPLETE) {
+		pid := g.state.turn
+		if err := g.PlacePiece(pid, g.players[pid].possiblePlacements.Any().ToSlice()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analysis := waitForAnalysis(t, g)
+
+	snap := g.Snapshot()
+	if snap.Analysis == nil {
+		t.Fatal("expected the analysis to be saved with the game")
+	}
+	restored, err := RestoreGame(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := restored.Analysis(); err != nil || len(saved.Turns) != len(analysis.Turns) {
+		t.Errorf("expected the saved analysis back, got %v, %v", saved, err)
+	}
+
+	// games saved without one are only analysed once it is asked for
+	snap.Analysis = nil
+	restored, err = RestoreGame(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.analyzing {
+		t.Error("restoring a game shouldn't start analysing it")
+	}
+	if _, err := restored.Analysis(); !errors.Is(err, ErrAnalysisPending) {
+		t.Errorf("expected the analysis to start on request, got %v", err)
+	}
+	if again := waitForAnalysis(t, restored); len(again.Turns) != len(analysis.Turns) {
+		t.Errorf("expected %d turns, got %d", len(analysis.Turns), len(again.Turns))
+	}
+}
+
+func waitForAnalysis(t *testing.T, g *Game) *types.Analysis {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		analysis, err := g.Analysis()
+		if err == nil {
+			return analysis
+		}
+		if !errors.Is(err, ErrAnalysisPending) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("analysis never finished")
+	return nil
+}
+
+func TestAnalysisSkipsTakebacks(t *testing.T) {
+	origins := map[types.PlayerID]types.Point{1: {X: 0, Y: 0}, 2: {X: 4, Y: 4}}
+	board := newTestBoard(5, origins)
+	pieces := PieceSet{}
+	pieces.Add(monomino)
+	pieces.Add(domino)
+
+	history := []types.Move{
+		{Seq: 0, Type: MOVE_PLACE, PID: 1, Placement: types.Placement{{X: 0, Y: 0}}},
+		{Seq: 1, Type: MOVE_PLACE, PID: 2, Placement: types.Placement{{X: 4, Y: 4}, {X: 4, Y: 3}}},
+		{Seq: 2, Type: MOVE_PLACE, PID: 1, Placement: types.Placement{{X: 1, Y: 1}, {X: 1, Y: 2}}},
+		{Seq: 3, Type: MOVE_TAKEBACK, PID: 1, Placement: types.Placement{{X: 1, Y: 1}, {X: 1, Y: 2}}},
+		{Seq: 4, Type: MOVE_PLACE, PID: 1, Placement: types.Placement{{X: 1, Y: 1}, {X: 2, Y: 1}}},
+	}
+	analysis := analyzeGame(board, pieces, []types.PlayerID{1, 2}, history)
+
+	if len(analysis.Turns) != 3 || analysis.Turns[2].Seq != 4 {
+		t.Fatalf("expected the taken back turn to be dropped, got %+v", analysis.Turns)
+	}
+	if analysis.Players[0].Territory != 3 || analysis.Players[1].Territory != 2 {
+		t.Errorf("unexpected territory %+v", analysis.Players)
+	}
+	if analysis.Players[0].Mobility[3] != 0 {
+		t.Errorf("player 1 has no pieces left, got mobility %v", analysis.Players[0].Mobility)
+	}
+}
@@ -0,0 +1,234 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"math/bits"
+)
+
+// One bit per cell of the board. Cell (x, y) is bit x*maxY + y.
+type bitboard []uint64
+
+func newBitboard(cells int) bitboard {
+	return make(bitboard, (cells+63)/64)
+}
+
+func (bb bitboard) has(i int) bool {
+	return bb[i/64]&(1<<(i%64)) != 0
+}
+
+func (bb bitboard) set(i int) {
+	bb[i/64] |= 1 << (i % 64)
+}
+
+func (bb bitboard) clear(i int) {
+	bb[i/64] &^= 1 << (i % 64)
+}
+
+func (bb bitboard) copy() bitboard {
+	cpy := make(bitboard, len(bb))
+	copy(cpy, bb)
+	return cpy
+}
+
+func (bb bitboard) or(other bitboard) bitboard {
+	for i := range bb {
+		bb[i] |= other[i]
+	}
+	return bb
+}
+
+func (bb bitboard) and(other bitboard) bitboard {
+	for i := range bb {
+		bb[i] &= other[i]
+	}
+	return bb
+}
+
+func (bb bitboard) andNot(other bitboard) bitboard {
+	for i := range bb {
+		bb[i] &^= other[i]
+	}
+	return bb
+}
+
+// A copy with every bit moved n cells toward higher indices (or lower, for negative n)
+func (bb bitboard) shift(n int) bitboard {
+	res := make(bitboard, len(bb))
+	if n >= 0 {
+		words, offset := n/64, uint(n%64)
+		for i := len(bb) - 1; i >= words; i-- {
+			res[i] = bb[i-words] << offset
+			if offset > 0 && i-words-1 >= 0 {
+				res[i] |= bb[i-words-1] >> (64 - offset)
+			}
+		}
+	} else {
+		n = -n
+		words, offset := n/64, uint(n%64)
+		for i := 0; i+words < len(bb); i++ {
+			res[i] = bb[i+words] >> offset
+			if offset > 0 && i+words+1 < len(bb) {
+				res[i] |= bb[i+words+1] << (64 - offset)
+			}
+		}
+	}
+	return res
+}
+
+// Call fn with the index of every set bit
+func (bb bitboard) each(fn func(i int)) {
+	for w, word := range bb {
+		for word != 0 {
+			fn(w*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
+
+// Masks describing where a player may and may not play, derived from their territory
+type playerMasks struct {
+	territory bitboard
+	edges     bitboard // cells sharing a side with the territory
+	diagonals bitboard // cells touching the territory at a corner
+	stale     bool
+}
+
+// Bitboard index kept alongside the board layout
+type boardBits struct {
+	cells    int
+	height   int      // cells per column, maxY
+	playable bitboard // not reserved
+	occupied bitboard // covered by a piece
+	origins  bitboard // starting squares not yet covered
+	firstRow bitboard // cells with y == 0
+	lastRow  bitboard // cells with y == maxY-1
+	players  map[types.Owner]*playerMasks
+}
+
+func (b *Board) index() *boardBits {
+	if b.bits == nil {
+		b.bits = b.buildBits()
+	}
+	return b.bits
+}
+
+func (b *Board) buildBits() *boardBits {
+	cells := int(b.maxX * b.maxY)
+	bb := &boardBits{
+		cells:    cells,
+		height:   int(b.maxY),
+		playable: newBitboard(cells),
+		occupied: newBitboard(cells),
+		origins:  newBitboard(cells),
+		firstRow: newBitboard(cells),
+		lastRow:  newBitboard(cells),
+		players:  make(map[types.Owner]*playerMasks),
+	}
+	for x := 0; x < int(b.maxX); x++ {
+		bb.firstRow.set(x * bb.height)
+		bb.lastRow.set(x*bb.height + bb.height - 1)
+		for y := 0; y < int(b.maxY); y++ {
+			i := x*bb.height + y
+			sq := b.layout[x][y]
+			switch {
+			case sq&types.RESERVED != 0:
+				continue
+			case sq.IsVacant():
+				if sq.IsOrigin() {
+					bb.origins.set(i)
+				}
+			default:
+				bb.occupied.set(i)
+				bb.player(sq).territory.set(i)
+			}
+			bb.playable.set(i)
+		}
+	}
+	return bb
+}
+
+func (bb *boardBits) copy() *boardBits {
+	cpy := *bb
+	cpy.playable = bb.playable.copy()
+	cpy.occupied = bb.occupied.copy()
+	cpy.origins = bb.origins.copy()
+	cpy.players = make(map[types.Owner]*playerMasks, len(bb.players))
+	for owner, masks := range bb.players {
+		cpy.players[owner] = &playerMasks{territory: masks.territory.copy(), stale: true}
+	}
+	return &cpy
+}
+
+// The masks for a player, which may be stale. Only the territory is kept up to date.
+func (bb *boardBits) player(owner types.Owner) *playerMasks {
+	owner &= types.PLAYER_MASK
+	masks, ok := bb.players[owner]
+	if !ok {
+		masks = &playerMasks{territory: newBitboard(bb.cells), stale: true}
+		bb.players[owner] = masks
+	}
+	return masks
+}
+
+// The masks for a player, recomputing the edges and diagonals if the territory has changed
+func (bb *boardBits) masks(owner types.Owner) *playerMasks {
+	masks := bb.player(owner)
+	if masks.stale {
+		beside := bb.trim(masks.territory.shift(bb.height).or(masks.territory.shift(-bb.height)))
+		masks.edges = bb.vertical(masks.territory).or(beside)
+		masks.diagonals = bb.vertical(beside)
+		masks.stale = false
+	}
+	return masks
+}
+
+// Cells directly above or below any cell in the mask
+func (bb *boardBits) vertical(mask bitboard) bitboard {
+	up := mask.copy().andNot(bb.lastRow).shift(1)
+	down := mask.copy().andNot(bb.firstRow).shift(-1)
+	return bb.trim(up.or(down))
+}
+
+// Clear any bits past the last cell
+func (bb *boardBits) trim(mask bitboard) bitboard {
+	if extra := bb.cells % 64; extra != 0 {
+		mask[len(mask)-1] &= (1 << extra) - 1
+	}
+	return mask
+}
+
+func (bb *boardBits) occupy(i int, owner types.Owner) {
+	if owner.IsVacant() {
+		if owner.IsOrigin() {
+			bb.origins.set(i)
+		}
+		return
+	}
+	bb.occupied.set(i)
+	bb.origins.clear(i)
+	masks := bb.player(owner)
+	masks.territory.set(i)
+	masks.stale = true
+}
+
+func (bb *boardBits) vacate(i int, previous types.Owner) {
+	bb.occupied.clear(i)
+	bb.origins.clear(i)
+	if !previous.IsVacant() {
+		masks := bb.player(previous)
+		masks.territory.clear(i)
+		masks.stale = true
+	}
+}
+
+// Bit index of a square, if it's on the board
+func (b *Board) cell(square types.Point) (int, bool) {
+	if square.X < 0 || square.X >= int(b.maxX) || square.Y < 0 || square.Y >= int(b.maxY) {
+		return 0, false
+	}
+	return square.X*int(b.maxY) + square.Y, true
+}
+
+func (b *Board) point(i int) types.Point {
+	return types.Point{X: i / int(b.maxY), Y: i % int(b.maxY)}
+}
@@ -0,0 +1,208 @@
+package game
+
+import (
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"math/rand"
+	"testing"
+)
+
+// The cell by cell checks the bitboard replaced, kept to test and benchmark against
+
+func (b *Board) legacyValidPlacement(points utilities.Set[types.Point], owner types.Owner) bool {
+	valid := false
+	for pt := range points {
+		if !b.inbounds(pt) || !b.vacant(pt) || b.legacyHasSelfSide(pt, owner) || b.isOriginForOther(pt, owner) {
+			return false
+		}
+		valid = valid || b.isStartingSquare(pt, owner) || b.legacyHasCorner(pt, owner)
+	}
+	return valid
+}
+
+func (b *Board) legacyHasSelfSide(pt types.Point, owner types.Owner) bool {
+	for _, dir := range []types.Direction{types.LEFT, types.RIGHT, types.UP, types.DOWN} {
+		adj := pt.GetAdjacent(dir)
+		if b.inbounds(adj) && b.occupiedByPlayer(adj, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Board) legacyHasCorner(pt types.Point, owner types.Owner) bool {
+	for _, vert := range []types.Direction{types.UP, types.DOWN} {
+		for _, horiz := range []types.Direction{types.LEFT, types.RIGHT} {
+			diag := pt.GetAdjacent(vert).GetAdjacent(horiz)
+			if b.inbounds(diag) && b.occupiedByPlayer(diag, owner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *Board) legacyPlayableCorners(pid types.PlayerID) []types.Point {
+	owner := types.Owner(pid)
+	unique := utilities.NewSet([]types.Point{})
+	for ii := 0; ii < int(b.maxX); ii++ {
+		for jj := 0; jj < int(b.maxY); jj++ {
+			pt := types.Point{X: ii, Y: jj}
+			if b.inbounds(pt) && b.vacant(pt) && b.legacyHasCorner(pt, owner) && !b.legacyHasSelfSide(pt, owner) {
+				unique.Add(pt)
+			}
+		}
+	}
+	return unique.ToSlice()
+}
+
+// A board with many players a few moves into the game
+func newBusyBoard(tb testing.TB, players int, moves int) *Board {
+	tb.Helper()
+	rng := rand.New(rand.NewSource(1))
+	pids := make([]types.PlayerID, players)
+	for ii := range pids {
+		pids[ii] = types.PlayerID(ii + 1)
+	}
+	pieces, pixels, err := cachedPieceSet(5)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	board, err := NewBoard(CircleShape{}, pids, pixels, 0.5)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	remaining := make(map[types.PlayerID]PieceSet, players)
+	for _, pid := range pids {
+		remaining[pid] = pieces.Copy()
+	}
+	for ii := 0; ii < moves; ii++ {
+		for _, pid := range pids {
+			placements := board.findPlacements(pid, remaining[pid])
+			if len(placements) == 0 {
+				continue
+			}
+			choice := placements[rng.Intn(len(placements))]
+			if _, err := board.Place(choice, pid); err != nil {
+				tb.Fatal(err)
+			}
+			left := remaining[pid]
+			left.Remove(PieceFromPoints(choice))
+		}
+	}
+	return board
+}
+
+// Every orientation of every piece, anchored at each of the player's corners
+func candidatePlacements(board *Board, pid types.PlayerID) []utilities.Set[types.Point] {
+	pieces, _, _ := cachedPieceSet(5)
+	candidates := make([]utilities.Set[types.Point], 0)
+	for _, corner := range board.legacyPlayableCorners(pid) {
+		for piece := range pieces {
+			for _, orient := range orientationsOf(piece) {
+				for _, origin := range orient.offsets {
+					points := utilities.NewSet([]types.Point{}, len(orient.offsets))
+					for _, pp := range orient.offsets {
+						points.Add(pp.Translate(corner.X-origin.X, corner.Y-origin.Y))
+					}
+					candidates = append(candidates, points)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+func TestBitboardMatchesLayout(t *testing.T) {
+	board := newBusyBoard(t, 6, 4)
+
+	for pid := range board.origins {
+		owner := types.Owner(pid)
+
+		expected := utilities.NewSet(board.legacyPlayableCorners(pid))
+		got := utilities.NewSet(board.playableCorners(pid, board.findTerritory(owner)))
+		if expected.Size() != got.Size() {
+			t.Errorf("player %d: expected corners %v, got %v", pid, expected.ToSlice(), got.ToSlice())
+		}
+		for pt := range expected {
+			if !got.Has(pt) {
+				t.Errorf("player %d: missing corner %v", pid, pt)
+			}
+		}
+
+		for ii := -1; ii <= int(board.maxX); ii++ {
+			for jj := -1; jj <= int(board.maxY); jj++ {
+				pt := types.Point{X: ii, Y: jj}
+				if !board.inbounds(pt) {
+					continue
+				}
+				if board.HasCorner(pt, owner) != board.legacyHasCorner(pt, owner) {
+					t.Errorf("player %d: corner mismatch at %v", pid, pt)
+				}
+				if board.hasSelfSide(pt, owner) != board.legacyHasSelfSide(pt, owner) {
+					t.Errorf("player %d: side mismatch at %v", pid, pt)
+				}
+			}
+		}
+
+		for _, points := range candidatePlacements(board, pid) {
+			if board.validPlacement(points, owner) != board.legacyValidPlacement(points, owner) {
+				t.Fatalf("player %d: placement %v disagrees", pid, points.ToSlice())
+			}
+		}
+	}
+}
+
+func TestBitboardRemove(t *testing.T) {
+	board := newTestBoard(6, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}, 2: {X: 5, Y: 5}})
+	placement := utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}})
+	if _, err := board.Place(placement, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !board.HasCorner(types.Point{X: 1, Y: 2}, 1) {
+		t.Errorf("expected a corner after placing")
+	}
+
+	board.Remove(placement)
+	if board.HasCorner(types.Point{X: 1, Y: 2}, 1) || len(board.findTerritory(1)) != 0 {
+		t.Errorf("removed placement still counted")
+	}
+	if !board.validPlacement(utilities.NewSet([]types.Point{{X: 0, Y: 0}}), 1) {
+		t.Errorf("origin should be playable again")
+	}
+}
+
+func BenchmarkBoard(b *testing.B) {
+	for _, players := range []int{4, 8, 16} {
+		board := newBusyBoard(b, players, 3)
+		candidates := candidatePlacements(board, 1)
+
+		b.Run(fmt.Sprintf("validPlacement/bitboard/%d-players", players), func(b *testing.B) {
+			for ii := 0; ii < b.N; ii++ {
+				for _, points := range candidates {
+					board.validPlacement(points, 1)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("validPlacement/layout/%d-players", players), func(b *testing.B) {
+			for ii := 0; ii < b.N; ii++ {
+				for _, points := range candidates {
+					board.legacyValidPlacement(points, 1)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("playableCorners/bitboard/%d-players", players), func(b *testing.B) {
+			for ii := 0; ii < b.N; ii++ {
+				board.bits.players[1].stale = true
+				board.playableCorners(1, board.findTerritory(1))
+			}
+		})
+		b.Run(fmt.Sprintf("playableCorners/layout/%d-players", players), func(b *testing.B) {
+			for ii := 0; ii < b.N; ii++ {
+				board.legacyPlayableCorners(1)
+			}
+		})
+	}
+}
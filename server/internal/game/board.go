@@ -2,9 +2,9 @@ package game
 
 import (
 	"errors"
+	"fmt"
 	"gobloks/internal/types"
 	"gobloks/internal/utilities"
-	"math"
 	"strings"
 	"sync"
 )
@@ -13,9 +13,10 @@ type Board struct {
 	layout     [][]types.Owner
 	origins    map[types.PlayerID]types.Point
 	maxX, maxY uint
+	bits       *boardBits // built from the layout on first use
 }
 
-func NewBoard(players []types.PlayerID, pixelsPerPlayer uint, tighteningFactor float64) (*Board, error) {
+func NewBoard(shape BoardShape, players []types.PlayerID, pixelsPerPlayer uint, tighteningFactor float64) (*Board, error) {
 
 	numPlayers := uint(len(players))
 	if numPlayers == 0 {
@@ -23,51 +24,39 @@ func NewBoard(players []types.PlayerID, pixelsPerPlayer uint, tighteningFactor f
 	}
 
 	maxOccupiedPixels := numPlayers * pixelsPerPlayer
-	// maxOccupancy ~= tighteningFactor * pi * r * r
-	radius := uint(math.Sqrt(float64(maxOccupiedPixels) / tighteningFactor / math.Pi))
+	// maxOccupancy ~= tighteningFactor * area
+	area := uint(float64(maxOccupiedPixels) / tighteningFactor)
 
-	diameter := (radius * 2) + 1
-
-	if diameter < radius {
-		// overflow
-		return nil, errors.New("radius too large")
+	playable, origins, err := shape.Carve(numPlayers, area)
+	if err != nil {
+		return nil, err
 	}
+	if len(playable) == 0 || len(playable[0]) == 0 {
+		return nil, errors.New("empty board")
+	}
+
 	board := Board{
-		layout:  make([][]types.Owner, diameter),
+		layout:  make([][]types.Owner, len(playable)),
 		origins: make(map[types.PlayerID]types.Point, numPlayers),
-		maxX:    diameter,
-		maxY:    diameter,
+		maxX:    uint(len(playable)),
+		maxY:    uint(len(playable[0])),
 	}
 	for i := range board.layout {
-		board.layout[i] = make([]types.Owner, diameter)
+		board.layout[i] = make([]types.Owner, board.maxY)
 		for j := range board.layout[i] {
-			board.layout[i][j] = types.RESERVED
-		}
-	}
-
-	circle := utilities.BresenhamCircle(radius, types.Point{X: int(radius), Y: int(radius)})
-
-	// clear out the playable region
-	for pt := range circle.Circumference {
-		rowSize := circle.Center.X - pt.X
-		var offset int
-		if rowSize < 0 {
-			rowSize = 1 - rowSize
-			offset = circle.Center.X
-		} else {
-			offset = pt.X
-
-		}
-
-		for i := 0; i < rowSize; i++ {
-			board.layout[pt.Y][offset+i] = types.VACANT
+			if playable[i][j] {
+				board.layout[i][j] = types.VACANT
+			} else {
+				board.layout[i][j] = types.RESERVED
+			}
 		}
 	}
 
-	angleDelta := (2 * math.Pi) / float64(numPlayers)
 	for ii, pid := range players {
-		pt := circle.PointOnCircle(angleDelta * float64(ii))
-		board.occupy(pt, types.Owner(pid)|types.ORIGIN|types.VACANT)
+		pt := origins[ii]
+		if err := board.occupy(pt, types.Owner(pid)|types.ORIGIN|types.VACANT); err != nil {
+			return nil, fmt.Errorf("origin for player %d: %w", pid, err)
+		}
 		board.origins[pid] = pt
 	}
 
@@ -88,6 +77,9 @@ func (b *Board) Copy() *Board {
 	for pid, pt := range b.origins {
 		boardCopy.origins[pid] = pt
 	}
+	if b.bits != nil {
+		boardCopy.bits = b.bits.copy()
+	}
 	return &boardCopy
 }
 
@@ -122,12 +114,16 @@ func (b *Board) occupy(square types.Point, owner types.Owner) error {
 	} else if !b.vacant(square) {
 		return errors.New("square is occupied")
 	}
+	i, _ := b.cell(square)
+	b.index().occupy(i, owner)
 	b.layout[square.X][square.Y] = owner
 	return nil
 }
 
 func (b *Board) vacate(square types.Point) {
 	if b.inbounds(square) {
+		i, _ := b.cell(square)
+		b.index().vacate(i, b.layout[square.X][square.Y])
 		b.layout[square.X][square.Y] = types.VACANT
 	}
 }
@@ -172,38 +168,34 @@ func (b *Board) occupiedByPlayer(square types.Point, owner types.Owner) bool {
 }
 
 func (b *Board) validPlacement(points utilities.Set[types.Point], owner types.Owner) bool {
+	bits := b.index()
+	masks := bits.masks(owner)
+	originPt, hasOrigin := b.origins[types.PlayerID(owner&types.PLAYER_MASK)]
+	origin, _ := b.cell(originPt)
+
 	valid := false
 	for pt := range points {
-		if !b.inbounds(pt) || !b.vacant(pt) || b.hasSelfSide(pt, owner) || b.isOriginForOther(pt, owner) {
+		i, ok := b.cell(pt)
+		if !ok || !bits.playable.has(i) || bits.occupied.has(i) || masks.edges.has(i) {
 			return false
 		}
-		valid = valid || b.isStartingSquare(pt, owner) || b.HasCorner(pt, owner)
+		isOrigin := bits.origins.has(i)
+		if isOrigin && !(hasOrigin && i == origin) {
+			return false // someone else's starting square
+		}
+		valid = valid || isOrigin || masks.diagonals.has(i)
 	}
 	return valid
 }
 
 func (b *Board) hasSelfSide(pt types.Point, owner types.Owner) bool {
-	l := pt.GetAdjacent(types.LEFT)
-	r := pt.GetAdjacent(types.RIGHT)
-	u := pt.GetAdjacent(types.UP)
-	d := pt.GetAdjacent(types.DOWN)
-
-	return ((b.inbounds(l) && b.occupiedByPlayer(l, owner)) ||
-		(b.inbounds(r) && b.occupiedByPlayer(r, owner)) ||
-		(b.inbounds(u) && b.occupiedByPlayer(u, owner)) ||
-		(b.inbounds(d) && b.occupiedByPlayer(d, owner)))
+	i, ok := b.cell(pt)
+	return ok && b.index().masks(owner).edges.has(i)
 }
 
 func (b *Board) HasCorner(pt types.Point, owner types.Owner) bool {
-	ul := pt.GetAdjacent(types.UP).GetAdjacent(types.LEFT)
-	ur := pt.GetAdjacent(types.UP).GetAdjacent(types.RIGHT)
-	dl := pt.GetAdjacent(types.DOWN).GetAdjacent(types.LEFT)
-	dr := pt.GetAdjacent(types.DOWN).GetAdjacent(types.RIGHT)
-
-	return ((b.inbounds(ul) && b.occupiedByPlayer(ul, owner)) ||
-		(b.inbounds(ur) && b.occupiedByPlayer(ur, owner)) ||
-		(b.inbounds(dl) && b.occupiedByPlayer(dl, owner)) ||
-		(b.inbounds(dr) && b.occupiedByPlayer(dr, owner)))
+	i, ok := b.cell(pt)
+	return ok && b.index().masks(owner).diagonals.has(i)
 }
 
 func (b *Board) Place(points utilities.Set[types.Point], player types.PlayerID) (bool, error) {
@@ -223,6 +215,18 @@ func (b *Board) Place(points utilities.Set[types.Point], player types.PlayerID)
 	return true, nil
 }
 
+// Lift a placement off the board, restoring any starting squares it covered
+func (b *Board) Remove(points utilities.Set[types.Point]) {
+	for pt := range points {
+		b.vacate(pt)
+		for pid, origin := range b.origins {
+			if origin.Is(pt) {
+				b.occupy(pt, types.Owner(pid)|types.ORIGIN|types.VACANT)
+			}
+		}
+	}
+}
+
 func (b *Board) getOrigin(player types.PlayerID) types.Point {
 	return b.origins[player]
 }
@@ -231,39 +235,28 @@ func (b *Board) getPlacementsAtPoint(
 	pt types.Point,
 	owner types.Owner,
 	pieces PieceSet,
-) utilities.LinkedList[utilities.Set[types.Point]] {
+) []utilities.Set[types.Point] {
+
+	res := make([]utilities.Set[types.Point], 0)
 
-	res := &utilities.Node[utilities.Set[types.Point]]{}
-	head := res
+	// bring the masks up to date before they're shared between goroutines
+	b.index().masks(owner)
 
 	var wg sync.WaitGroup
 	chFound := make(chan utilities.Set[types.Point])
 
 	findPiecePlacements := func(piece Piece) {
 		defer wg.Done()
-		attemptedPermutations := utilities.NewSet([]Piece{}, 8)
 
-		for j := 0; j < 2; j++ {
-			piece = piece.Reflect(types.X)
-
-			for i := 0; i < 4; i++ {
-				piece = piece.Rotate90()
-				if attemptedPermutations.Has(piece) {
-					continue // Already tried this one
+		// Check all possible placements
+		for _, orient := range orientationsOf(piece) {
+			for _, origin := range orient.offsets {
+				absPoints := utilities.NewSet([]types.Point{}, len(orient.offsets))
+				for _, pp := range orient.offsets {
+					absPoints.Add(pp.Translate(pt.X-origin.X, pt.Y-origin.Y))
 				}
-				attemptedPermutations.Add(piece)
-
-				// Check all possible placements
-				// TODO: Optimize?
-				piecePoints := piece.ToPoints()
-				for origin := range piecePoints {
-					absPoints := utilities.NewSet([]types.Point{}, len(piecePoints))
-					for pp := range piecePoints {
-						absPoints.Add(pp.Translate(pt.X-origin.X, pt.Y-origin.Y))
-					}
-					if b.validPlacement(absPoints, owner) {
-						chFound <- absPoints
-					}
+				if b.validPlacement(absPoints, owner) {
+					chFound <- absPoints
 				}
 			}
 		}
@@ -279,8 +272,7 @@ func (b *Board) getPlacementsAtPoint(
 	go func() {
 		defer resultGroup.Done()
 		for found := range chFound {
-			head.Next = &utilities.Node[utilities.Set[types.Point]]{Value: found}
-			head = head.Next
+			res = append(res, found)
 		}
 	}()
 
@@ -292,19 +284,15 @@ func (b *Board) getPlacementsAtPoint(
 }
 
 func (b *Board) findTerritory(o types.Owner) []types.Point {
-	territory := make([]types.Point, 0, b.maxX*b.maxY)
-	for ii := 0; ii < int(b.maxX); ii++ {
-		for jj := 0; jj < int(b.maxY); jj++ {
-			if b.occupiedByPlayer(types.Point{X: ii, Y: jj}, o) {
-				territory = append(territory, types.Point{X: ii, Y: jj})
-			}
-		}
-	}
+	territory := make([]types.Point, 0)
+	b.index().player(o).territory.each(func(i int) {
+		territory = append(territory, b.point(i))
+	})
 	return territory
 }
 
 func (b *Board) findCorners(territory []types.Point, owner types.Owner) []types.Point {
-	corners := make([]types.Point, 0, b.maxX*b.maxY)
+	corners := make([]types.Point, 0, len(territory)*4)
 	for _, pt := range territory {
 		corners = append(corners, b.getFreeCorners(pt, owner)...)
 	}
@@ -312,29 +300,93 @@ func (b *Board) findCorners(territory []types.Point, owner types.Owner) []types.
 }
 
 func (b *Board) getFreeCorners(pt types.Point, owner types.Owner) []types.Point {
+	bits := b.index()
+	territory := bits.player(owner).territory
+	owned := func(sq types.Point) bool {
+		i, ok := b.cell(sq)
+		return ok && territory.has(i)
+	}
+	free := func(sq types.Point) bool {
+		i, ok := b.cell(sq)
+		return ok && bits.playable.has(i) && !bits.occupied.has(i)
+	}
+
 	l := pt.GetAdjacent(types.LEFT)
 	r := pt.GetAdjacent(types.RIGHT)
 	u := pt.GetAdjacent(types.UP)
 	d := pt.GetAdjacent(types.DOWN)
 
-	ul := u.GetAdjacent(types.LEFT)
-	ur := u.GetAdjacent(types.RIGHT)
-	dl := d.GetAdjacent(types.LEFT)
-	dr := d.GetAdjacent(types.RIGHT)
-
 	vacancies := make([]types.Point, 0, 4)
-
-	if b.inbounds(ul) && b.vacant(ul) && !b.occupiedByPlayer(u, owner) && !b.occupiedByPlayer(l, owner) {
-		vacancies = append(vacancies, ul)
+	for _, diag := range []struct{ corner, side1, side2 types.Point }{
+		{u.GetAdjacent(types.LEFT), u, l},
+		{u.GetAdjacent(types.RIGHT), u, r},
+		{d.GetAdjacent(types.LEFT), d, l},
+		{d.GetAdjacent(types.RIGHT), d, r},
+	} {
+		if free(diag.corner) && !owned(diag.side1) && !owned(diag.side2) {
+			vacancies = append(vacancies, diag.corner)
+		}
 	}
-	if b.inbounds(ur) && b.vacant(ur) && !b.occupiedByPlayer(u, owner) && !b.occupiedByPlayer(r, owner) {
-		vacancies = append(vacancies, ur)
+	return vacancies
+}
+
+// Corners from which the player could legally extend their territory.
+// A player with no territory yet may only start from their origin.
+func (b *Board) playableCorners(pid types.PlayerID, territory []types.Point) []types.Point {
+	owner := types.Owner(pid)
+	if len(territory) == 0 {
+		origin, ok := b.origins[pid]
+		if ok && b.isStartingSquare(origin, owner) {
+			return []types.Point{origin}
+		}
+		return []types.Point{}
 	}
-	if b.inbounds(dl) && b.vacant(dl) && !b.occupiedByPlayer(d, owner) && !b.occupiedByPlayer(l, owner) {
-		vacancies = append(vacancies, dl)
+
+	bits := b.index()
+	masks := bits.masks(owner)
+	corners := masks.diagonals.copy().and(bits.playable).andNot(bits.occupied).andNot(masks.edges)
+
+	playable := make([]types.Point, 0)
+	corners.each(func(i int) {
+		playable = append(playable, b.point(i))
+	})
+	return playable
+}
+
+// Every distinct legal placement of the given pieces for a player
+func (b *Board) findPlacements(pid types.PlayerID, pieces PieceSet) []utilities.Set[types.Point] {
+	territory := b.findTerritory(types.Owner(pid))
+	seen := utilities.NewSet([]placementKey{})
+	placements := make([]utilities.Set[types.Point], 0)
+
+	for _, corner := range b.playableCorners(pid, territory) {
+		for _, plc := range b.getPlacementsAtPoint(corner, types.Owner(pid), pieces) {
+			key := keyOf(plc)
+			if !seen.Has(key) {
+				seen.Add(key)
+				placements = append(placements, plc)
+			}
+		}
 	}
-	if b.inbounds(dr) && b.vacant(dr) && !b.occupiedByPlayer(d, owner) && !b.occupiedByPlayer(r, owner) {
-		vacancies = append(vacancies, dr)
+	return placements
+}
+
+// Uniquely identifies a set of absolute points on the board
+type placementKey struct {
+	repr   uint64
+	offset types.Point
+}
+
+func keyOf(points utilities.Set[types.Point]) placementKey {
+	var repr uint64
+	normal, offset := utilities.NormalizeToOrigin(points)
+	for pt := range normal {
+		repr |= pointTo64(pieceCoord{uint8(pt.X), uint8(pt.Y)})
 	}
-	return vacancies
+	return placementKey{repr, offset}
+}
+
+// The squares a key was made from
+func (key placementKey) points() utilities.Set[types.Point] {
+	return utilities.Translate(Piece{key.repr, key.repr}.ToPoints(), key.offset.X, key.offset.Y)
 }
@@ -0,0 +1,221 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"math"
+)
+
+// Board shapes selectable in the game config
+const (
+	SHAPE_CIRCLE  = "circle"
+	SHAPE_SQUARE  = "square"
+	SHAPE_HEXAGON = "hexagon"
+	SHAPE_CUSTOM  = "custom"
+)
+
+// Characters understood in a custom board mask. Digits mark a player's origin.
+const (
+	MASK_PLAYABLE = '.'
+	MASK_BLOCKED  = '#'
+)
+
+// Decides which cells of a board are playable and where each player starts
+type BoardShape interface {
+	// Lay out a board for the given players with roughly area playable cells.
+	// The playable mask is indexed [x][y], and there is one origin per player.
+	Carve(numPlayers uint, area uint) (playable [][]bool, origins []types.Point, err error)
+}
+
+type CircleShape struct{}
+
+// Square board with players starting in the corners, as in the original game
+type SquareShape struct {
+	Side uint // 0 to size the board from the area
+}
+
+// The original game's board, used for its piece set
+const CLASSIC_SIDE = 20
+
+type HexagonShape struct{}
+
+// Hand drawn board, one string per row
+type MaskShape struct {
+	Rows []string
+}
+
+func boardShape(config types.GameConfig) (BoardShape, error) {
+	switch config.Shape {
+	case "", SHAPE_CIRCLE:
+		return CircleShape{}, nil
+	case SHAPE_SQUARE:
+		if len(config.Pieces) == 0 && config.PieceSet == PIECES_CLASSIC {
+			return SquareShape{Side: CLASSIC_SIDE}, nil
+		}
+		return SquareShape{}, nil
+	case SHAPE_HEXAGON:
+		return HexagonShape{}, nil
+	case SHAPE_CUSTOM:
+		return MaskShape{config.Mask}, nil
+	}
+	return nil, fmt.Errorf("unknown board shape %q", config.Shape)
+}
+
+func newMask(width, height uint) [][]bool {
+	mask := make([][]bool, width)
+	for i := range mask {
+		mask[i] = make([]bool, height)
+	}
+	return mask
+}
+
+func (CircleShape) Carve(numPlayers uint, area uint) ([][]bool, []types.Point, error) {
+	// area ~= pi * r * r
+	radius := uint(math.Sqrt(float64(area) / math.Pi))
+	diameter := (radius * 2) + 1
+	if diameter < radius {
+		// overflow
+		return nil, nil, errors.New("radius too large")
+	}
+	mask := newMask(diameter, diameter)
+
+	circle := utilities.BresenhamCircle(radius, types.Point{X: int(radius), Y: int(radius)})
+
+	// clear out the playable region
+	for pt := range circle.Circumference {
+		rowSize := circle.Center.X - pt.X
+		var offset int
+		if rowSize < 0 {
+			rowSize = 1 - rowSize
+			offset = circle.Center.X
+		} else {
+			offset = pt.X
+		}
+
+		for i := 0; i < rowSize; i++ {
+			mask[pt.Y][offset+i] = true
+		}
+	}
+
+	origins := make([]types.Point, numPlayers)
+	angleDelta := (2 * math.Pi) / float64(numPlayers)
+	for ii := range origins {
+		origins[ii] = circle.PointOnCircle(angleDelta * float64(ii))
+	}
+	return mask, origins, nil
+}
+
+func (shape SquareShape) Carve(numPlayers uint, area uint) ([][]bool, []types.Point, error) {
+	if numPlayers > 4 {
+		return nil, nil, errors.New("a square board has room for at most 4 players")
+	}
+	side := shape.Side
+	if side == 0 {
+		side = max(uint(math.Ceil(math.Sqrt(float64(area)))), 2)
+	}
+	mask := newMask(side, side)
+	for x := range mask {
+		for y := range mask[x] {
+			mask[x][y] = true
+		}
+	}
+
+	last := int(side) - 1
+	if numPlayers == 2 {
+		// opposite corners, so two players start as far apart as possible
+		return mask, []types.Point{{X: 0, Y: 0}, {X: last, Y: last}}, nil
+	}
+	// clockwise in turn order, which also seats team partners opposite each other
+	corners := []types.Point{{X: 0, Y: 0}, {X: last, Y: 0}, {X: last, Y: last}, {X: 0, Y: last}}
+	return mask, corners[:numPlayers], nil
+}
+
+func (HexagonShape) Carve(numPlayers uint, area uint) ([][]bool, []types.Point, error) {
+	// trimming the corners of a (2r+1) square leaves ~3r^2 cells
+	radius := max(int(math.Sqrt(float64(area)/3)), 1)
+	side := uint(2*radius + 1)
+	mask := newMask(side, side)
+	for y := 0; y < int(side); y++ {
+		// rows narrow by a cell on each side every second row away from the middle
+		cut := abs(y-radius) / 2
+		for x := cut; x < int(side)-cut; x++ {
+			mask[x][y] = true
+		}
+	}
+
+	center := types.Point{X: radius, Y: radius}
+	origins := make([]types.Point, numPlayers)
+	angleDelta := (2 * math.Pi) / float64(numPlayers)
+	for ii := range origins {
+		origins[ii] = edgeOfMask(mask, center, angleDelta*float64(ii))
+	}
+	return mask, origins, nil
+}
+
+func (shape MaskShape) Carve(numPlayers uint, area uint) ([][]bool, []types.Point, error) {
+	if len(shape.Rows) == 0 || len(shape.Rows[0]) == 0 {
+		return nil, nil, errors.New("empty board mask")
+	}
+	width := len(shape.Rows[0])
+	mask := newMask(uint(width), uint(len(shape.Rows)))
+	origins := make([]types.Point, numPlayers)
+	found := make([]bool, numPlayers)
+
+	for y, row := range shape.Rows {
+		if len(row) != width {
+			return nil, nil, fmt.Errorf("mask row %d has %d cells, expected %d", y, len(row), width)
+		}
+		for x, cell := range []byte(row) {
+			switch {
+			case cell == MASK_BLOCKED:
+			case cell == MASK_PLAYABLE:
+				mask[x][y] = true
+			case cell >= '1' && cell <= '9':
+				player := uint(cell - '1')
+				if player >= numPlayers {
+					return nil, nil, fmt.Errorf("mask has an origin for player %c but the game has %d players", cell, numPlayers)
+				}
+				if found[player] {
+					return nil, nil, fmt.Errorf("mask has more than one origin for player %c", cell)
+				}
+				mask[x][y] = true
+				origins[player] = types.Point{X: x, Y: y}
+				found[player] = true
+			default:
+				return nil, nil, fmt.Errorf("invalid mask cell %q", cell)
+			}
+		}
+	}
+
+	for player, ok := range found {
+		if !ok {
+			return nil, nil, fmt.Errorf("mask has no origin for player %d", player+1)
+		}
+	}
+	return mask, origins, nil
+}
+
+// Walk out from the center in the direction of theta, returning the last playable cell
+func edgeOfMask(mask [][]bool, center types.Point, theta float64) types.Point {
+	edge := center
+	dx, dy := math.Cos(theta), math.Sin(theta)
+	for step := 1.0; ; step++ {
+		pt := types.Point{
+			X: center.X + int(math.Round(dx*step)),
+			Y: center.Y + int(math.Round(dy*step)),
+		}
+		if pt.X < 0 || pt.X >= len(mask) || pt.Y < 0 || pt.Y >= len(mask[pt.X]) || !mask[pt.X][pt.Y] {
+			return edge
+		}
+		edge = pt
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
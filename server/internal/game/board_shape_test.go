@@ -0,0 +1,115 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestSquareBoard(t *testing.T) {
+	pids := []types.PlayerID{1, 2, 3, 4}
+	board, err := NewBoard(SquareShape{}, pids, 89, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.maxX != 20 || board.maxY != 20 {
+		t.Errorf("expected a 20x20 board for 4 players, got %dx%d", board.maxX, board.maxY)
+	}
+	// clockwise, so players 1 and 3 partner from opposite corners in a 2 team game
+	corners := []types.Point{{X: 0, Y: 0}, {X: 19, Y: 0}, {X: 19, Y: 19}, {X: 0, Y: 19}}
+	for ii, pid := range pids {
+		if board.origins[pid] != corners[ii] {
+			t.Errorf("player %d should start at %v, got %v", pid, corners[ii], board.origins[pid])
+		}
+	}
+
+	board, err = NewBoard(SquareShape{}, pids[:2], 89, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := int(board.maxX) - 1
+	if board.origins[1] != (types.Point{X: 0, Y: 0}) || board.origins[2] != (types.Point{X: last, Y: last}) {
+		t.Errorf("two players should start in opposite corners, got %v and %v", board.origins[1], board.origins[2])
+	}
+
+	if _, err = NewBoard(SquareShape{}, []types.PlayerID{1, 2, 3, 4, 5}, 89, 0.9); err == nil {
+		t.Errorf("expected an error for 5 players on a square board")
+	}
+}
+
+func TestHexagonBoard(t *testing.T) {
+	pids := []types.PlayerID{1, 2, 3, 4, 5, 6}
+	board, err := NewBoard(HexagonShape{}, pids, 40, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.inbounds(types.Point{X: 0, Y: 0}) {
+		t.Errorf("hexagon corners should be reserved")
+	}
+	seen := map[types.Point]bool{}
+	for _, pid := range pids {
+		pt := board.origins[pid]
+		if !board.isStartingSquare(pt, types.Owner(pid)) || seen[pt] {
+			t.Errorf("bad origin %v for player %d", pt, pid)
+		}
+		seen[pt] = true
+	}
+}
+
+func TestMaskBoard(t *testing.T) {
+	rows := []string{
+		"1...#",
+		".....",
+		"#...2",
+	}
+	board, err := NewBoard(MaskShape{rows}, []types.PlayerID{1, 2}, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.maxX != 5 || board.maxY != 3 {
+		t.Errorf("expected a 5x3 board, got %dx%d", board.maxX, board.maxY)
+	}
+	if board.inbounds(types.Point{X: 4, Y: 0}) || board.inbounds(types.Point{X: 0, Y: 2}) {
+		t.Errorf("blocked cells should be reserved")
+	}
+	if board.origins[1] != (types.Point{X: 0, Y: 0}) || board.origins[2] != (types.Point{X: 4, Y: 2}) {
+		t.Errorf("unexpected origins %v", board.origins)
+	}
+
+	invalid := [][]string{
+		{},
+		{"1..", ".."},
+		{"1..", "..."},
+		{"1.3", "..2"},
+		{"1.1", "..2"},
+		{"1.x", "..2"},
+	}
+	for _, rows := range invalid {
+		if _, err := NewBoard(MaskShape{rows}, []types.PlayerID{1, 2}, 0, 1); err == nil {
+			t.Errorf("expected an error for mask %q", rows)
+		}
+	}
+}
+
+func TestGameBoardShape(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 5, Density: 0.9, Shape: SHAPE_SQUARE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.state.board.origins[1] != (types.Point{X: 0, Y: 0}) {
+		t.Errorf("expected player 1 to start in the corner")
+	}
+
+	for _, players := range []uint{2, 3, 4} {
+		g, err = InitGame("TEST", types.GameConfig{Players: players, PieceSet: PIECES_CLASSIC, Density: 0.9, Shape: SHAPE_SQUARE})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if board := g.state.board; board.maxX != CLASSIC_SIDE || board.maxY != CLASSIC_SIDE {
+			t.Errorf("expected the classic set to play on a 20x20 board with %d players, got %dx%d", players, board.maxX, board.maxY)
+		}
+	}
+
+	if _, err = InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 5, Density: 0.9, Shape: "triangle"}); err == nil {
+		t.Errorf("expected an error for an unknown shape")
+	}
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Bot difficulty levels
+const (
+	BOT_RANDOM types.BotLevel = iota + 1 // any legal placement
+	BOT_GREEDY                           // largest piece available
+	BOT_SEARCH                           // best placement found by the eval engine
+)
+
+const (
+	botThinkTime        = 750 * time.Millisecond
+	botSearchCandidates = 64
+)
+
+var botColors = []uint{0xe6194b, 0x3cb44b, 0x4363d8, 0xf58231, 0x911eb4, 0x42d4f4, 0xf032e6, 0xbfef45}
+
+var botNames = map[types.BotLevel]string{
+	BOT_RANDOM: "random",
+	BOT_GREEDY: "greedy",
+	BOT_SEARCH: "search",
+}
+
+type Bot struct {
+	level    types.BotLevel
+	thinking bool
+}
+
+func validBotLevel(level types.BotLevel) bool {
+	_, ok := botNames[level]
+	return ok
+}
+
+func botName(pid types.PlayerID, level types.BotLevel) string {
+	return fmt.Sprintf("Bot %d (%s)", pid, botNames[level])
+}
+
+func botColor(seat int) uint {
+	return botColors[seat%len(botColors)]
+}
+
+// Pick a placement from the candidates according to the bot's level
+func (bot *Bot) choose(
+	candidates []utilities.Set[types.Point],
+	state *EvalState,
+	engine *EvalEngine,
+) utilities.Set[types.Point] {
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch bot.level {
+	case BOT_GREEDY:
+		return largestPlacements(candidates, 1)[0]
+	case BOT_SEARCH:
+		best, _, err := engine.BestPlacement(state, largestPlacements(candidates, botSearchCandidates))
+		if err == nil {
+			return best
+		}
+		fmt.Println("bot search failed:", err)
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// Up to n of the largest placements, ties broken randomly
+func largestPlacements(candidates []utilities.Set[types.Point], n int) []utilities.Set[types.Point] {
+	shuffled := slices.Clone(candidates)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	slices.SortStableFunc(shuffled, func(a, b utilities.Set[types.Point]) int {
+		return b.Size() - a.Size()
+	})
+	return shuffled[:min(n, len(shuffled))]
+}
+
+// Start any bots that are able to move thinking about their next placement.
+// Must be called with the game lock held.
+func (g *Game) scheduleBots() {
+	if g.closed || g.state.status.Has(COMPLETE) {
+		return
+	}
+	for _, player := range g.players {
+		if player == nil || player.bot == nil || player.bot.thinking {
+			continue
+		}
+		if _, err := g.playerActionValid(player); err != nil {
+			continue
+		}
+		player.bot.thinking = true
+		go g.playBot(player)
+	}
+}
+
+func (g *Game) playBot(player *Player) {
+	time.Sleep(botThinkTime)
+
+	g.lock.Lock()
+	if _, err := g.playerActionValid(player); err != nil || g.closed || g.state.status.Has(COMPLETE) {
+		player.bot.thinking = false
+		g.lock.Unlock()
+		return
+	}
+	candidates := player.possiblePlacements.All()
+	state := g.evalState()
+	state.game.turn = player.state.pid // search from the bot's point of view
+	g.lock.Unlock()
+
+	choice := player.bot.choose(candidates, state, g.evalEngine)
+	if choice != nil {
+		err := g.PlacePiece(player.state.pid, choice.ToSlice())
+		if err != nil {
+			fmt.Println("bot placement failed:", err)
+		}
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	player.bot.thinking = false
+	if choice != nil {
+		g.scheduleBots() // may be able to move again
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"testing"
+	"time"
+)
+
+func TestBotsFillSeats(t *testing.T) {
+	_, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 1, Bots: []types.BotLevel{BOT_RANDOM, BOT_GREEDY}})
+	if err == nil {
+		t.Errorf("expected an error when no seat is left for a human")
+	}
+
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 1, TurnBased: true, Bots: []types.BotLevel{BOT_GREEDY}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.state.status.Has(FULL) {
+		t.Errorf("game should wait for the human player")
+	}
+
+	bot := g.players[2]
+	if bot == nil || bot.bot == nil || !bot.state.status.Has(BOT) {
+		t.Fatalf("expected the last seat to be taken by a bot")
+	}
+
+	pid, err := g.AddPlayer("human", 0xffffff, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != 1 || !g.state.status.Has(FULL) {
+		t.Fatalf("expected the human to take seat 1 and fill the game")
+	}
+
+	err = g.PlacePiece(pid, g.players[pid].possiblePlacements.Any().ToSlice())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.lock.Lock()
+		moved := len(g.state.board.findTerritory(types.Owner(2))) > 0
+		g.lock.Unlock()
+		if moved {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("bot never took its turn")
+}
+
+func TestGreedyBotPicksLargest(t *testing.T) {
+	candidates := []utilities.Set[types.Point]{
+		utilities.NewSet([]types.Point{{X: 0, Y: 0}}),
+		utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}}),
+		utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 1, Y: 0}}),
+	}
+	bot := &Bot{level: BOT_GREEDY}
+	for ii := 0; ii < 10; ii++ {
+		if choice := bot.choose(candidates, nil, nil); choice.Size() != 3 {
+			t.Errorf("expected the largest placement, got %v", choice.ToSlice())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+)
+
+type ErrorCode string
+
+// Reasons an action can be rejected
+const (
+	ERR_NOT_YOUR_TURN       ErrorCode = "NOT_YOUR_TURN"
+	ERR_PLAYER_INACTIVE     ErrorCode = "PLAYER_INACTIVE"
+	ERR_WAITING_FOR_PLAYERS ErrorCode = "WAITING_FOR_PLAYERS"
+	ERR_INVALID_PIECE       ErrorCode = "INVALID_PIECE"
+	ERR_PIECE_NOT_OWNED     ErrorCode = "PIECE_NOT_OWNED"
+	ERR_OUT_OF_BOUNDS       ErrorCode = "OUT_OF_BOUNDS"
+	ERR_OVERLAPS            ErrorCode = "OVERLAPS"
+	ERR_COVERS_OTHER_ORIGIN ErrorCode = "COVERS_OTHER_ORIGIN"
+	ERR_TOUCHES_OWN_EDGE    ErrorCode = "TOUCHES_OWN_EDGE"
+	ERR_NO_CORNER_CONTACT   ErrorCode = "NO_CORNER_CONTACT"
+	ERR_INVALID_PLACEMENT   ErrorCode = "INVALID_PLACEMENT"
+	ERR_INVALID_HINT        ErrorCode = "INVALID_HINT"
+	ERR_NO_HINTS_LEFT       ErrorCode = "NO_HINTS_LEFT"
+	ERR_NO_NEW_HINTS        ErrorCode = "NO_NEW_HINTS"
+	ERR_GAME_NOT_COMPLETE   ErrorCode = "GAME_NOT_COMPLETE"
+	ERR_GAME_OVER           ErrorCode = "GAME_OVER"
+	ERR_NOT_TURN_BASED      ErrorCode = "NOT_TURN_BASED"
+)
+
+// An error a client can act on, with a machine readable code
+type GameError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+func (e *GameError) Error() string {
+	return e.Message
+}
+
+func gameError(code ErrorCode, format string, args ...any) *GameError {
+	return &GameError{code, fmt.Sprintf(format, args...)}
+}
+
+// Work out why a placement isn't among the player's legal placements.
+// Must be called with the game lock held.
+func (g *Game) rejectPlacement(player *Player, placement []types.Point) *GameError {
+	piece, err := pieceFromBody(placement)
+	if err != nil {
+		return gameError(ERR_INVALID_PIECE, "invalid piece: %s", err)
+	}
+	if !player.state.pieces.Has(piece) {
+		return gameError(ERR_PIECE_NOT_OWNED, "you don't have this piece")
+	}
+
+	board := g.state.board
+	bits := board.index()
+	owner := types.Owner(player.state.pid)
+	masks := bits.masks(owner)
+	origin, hasOrigin := board.origins[player.state.pid]
+
+	for _, pt := range placement {
+		i, ok := board.cell(pt)
+		if !ok || !bits.playable.has(i) {
+			return gameError(ERR_OUT_OF_BOUNDS, "square (%d, %d) is off the board", pt.X, pt.Y)
+		}
+		if bits.occupied.has(i) {
+			return gameError(ERR_OVERLAPS, "square (%d, %d) is already covered", pt.X, pt.Y)
+		}
+		if bits.origins.has(i) && !(hasOrigin && origin.Is(pt)) {
+			return gameError(ERR_COVERS_OTHER_ORIGIN, "square (%d, %d) is another player's starting square", pt.X, pt.Y)
+		}
+		if masks.edges.has(i) {
+			return gameError(ERR_TOUCHES_OWN_EDGE, "square (%d, %d) touches the side of one of your pieces", pt.X, pt.Y)
+		}
+	}
+
+	if !board.validPlacement(utilities.NewSet(placement), owner) {
+		if len(board.findTerritory(owner)) == 0 {
+			return gameError(ERR_NO_CORNER_CONTACT, "your first piece must cover your starting square")
+		}
+		return gameError(ERR_NO_CORNER_CONTACT, "piece must touch the corner of one of your pieces")
+	}
+	return gameError(ERR_INVALID_PLACEMENT, "invalid placement")
+}
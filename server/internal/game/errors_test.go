@@ -0,0 +1,57 @@
+package game
+
+import (
+	"errors"
+	"gobloks/internal/types"
+	"testing"
+)
+
+func expectCode(t *testing.T, err error, code ErrorCode) {
+	t.Helper()
+	var gameErr *GameError
+	if !errors.As(err, &gameErr) {
+		t.Errorf("expected %s, got %v", code, err)
+	} else if gameErr.Code != code {
+		t.Errorf("expected %s, got %s (%s)", code, gameErr.Code, gameErr.Message)
+	}
+}
+
+func TestPlacementErrors(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xffffff, 0)
+	origin1 := g.state.board.origins[p1]
+
+	expectCode(t, g.PlacePiece(p1, types.Placement{origin1}), ERR_WAITING_FOR_PLAYERS)
+
+	p2, _ := g.AddPlayer("two", 0xffffff, 0)
+	origin2 := g.state.board.origins[p2]
+	center := types.Point{X: int(g.state.board.maxX / 2), Y: int(g.state.board.maxY / 2)}
+
+	expectCode(t, g.PlacePiece(p2, types.Placement{origin2}), ERR_NOT_YOUR_TURN)
+	expectCode(t, g.PlacePiece(p1, types.Placement{origin1, {X: origin1.X + 2, Y: origin1.Y}}), ERR_INVALID_PIECE)
+	expectCode(t, g.PlacePiece(p1, types.Placement{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}}), ERR_PIECE_NOT_OWNED)
+	expectCode(t, g.PlacePiece(p1, types.Placement{{X: -5, Y: -5}}), ERR_OUT_OF_BOUNDS)
+	expectCode(t, g.PlacePiece(p1, types.Placement{origin2}), ERR_COVERS_OTHER_ORIGIN)
+	expectCode(t, g.PlacePiece(p1, types.Placement{center}), ERR_NO_CORNER_CONTACT)
+
+	if err = g.PlacePiece(p1, types.Placement{origin1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.PlacePiece(p2, types.Placement{origin2}); err != nil {
+		t.Fatal(err)
+	}
+
+	var side types.Point
+	for _, dir := range []types.Direction{types.UP, types.DOWN, types.LEFT, types.RIGHT} {
+		if adj := origin1.GetAdjacent(dir); g.state.board.vacant(adj) {
+			side = adj
+			break
+		}
+	}
+	expectCode(t, g.PlacePiece(p1, types.Placement{origin1, side}), ERR_OVERLAPS)
+	expectCode(t, g.PlacePiece(p1, types.Placement{side}), ERR_PIECE_NOT_OWNED)
+	expectCode(t, g.PlacePiece(p1, types.Placement{side, side.GetAdjacent(types.UP)}), ERR_TOUCHES_OWN_EDGE)
+}
@@ -1,16 +1,21 @@
 package game
 
 import (
+	"errors"
 	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"math"
 )
 
 const (
-	WEIGHT_PLACEMENTS float64 = 1.0
-	WEIGHT_CORNERS    float64 = 1.0
+	WEIGHT_PLACEMENTS float64 = 0.05
+	WEIGHT_CORNERS    float64 = 1.5
 	WEIGHT_TERRITORY  float64 = 1.0
-	WEIGHT_OPEN_SPACE float64 = 1.0
+	WEIGHT_OPEN_SPACE float64 = 0.25
 )
 
+var errEvalCancelled = errors.New("evaluation cancelled")
+
 type EvalEngine struct {
 	depth    uint
 	chRecv   chan *EvalState
@@ -23,6 +28,42 @@ type EvalState struct {
 	players map[types.PlayerID]*PlayerState
 }
 
+func (s *EvalState) Copy() *EvalState {
+	players := make(map[types.PlayerID]*PlayerState, len(s.players))
+	for pid, player := range s.players {
+		if player != nil {
+			players[pid] = player.Copy()
+		} else {
+			players[pid] = nil // keep empty seats so turn order is preserved
+		}
+	}
+	return &EvalState{s.game.Copy(), players}
+}
+
+// place commits a placement for the player on turn and advances the turn
+func (s *EvalState) place(pid types.PlayerID, placement utilities.Set[types.Point]) {
+	s.game.board.Place(placement, pid)
+	s.players[pid].pieces.Remove(PieceFromPoints(placement))
+	s.advanceTurn()
+}
+
+// advanceTurn moves the turn to the next player who is still active
+func (s *EvalState) advanceTurn() {
+	numPlayers := len(s.players)
+	var nextUp types.PlayerID = PID_NONE
+	for i := 0; i < numPlayers; i++ {
+		maybeNext := types.PlayerID((int(s.game.turn)+i)%numPlayers) + 1
+		if player := s.players[maybeNext]; player != nil && !player.status.Has(DISABLED) {
+			nextUp = maybeNext
+			break
+		}
+	}
+	s.game.turn = nextUp
+	if nextUp == PID_NONE {
+		s.game.status.Set(COMPLETE)
+	}
+}
+
 func InitEvalEngine(depth uint) *EvalEngine {
 	return &EvalEngine{
 		depth:    depth,
@@ -52,58 +93,135 @@ func (engine *EvalEngine) Stop() {
 	close(engine.chResult)
 }
 
+// BestPlacement searches the position and returns the placement preferred for
+// the player on turn, along with the evaluation of the resulting line of play.
+// When candidates is nil, every legal placement for the player is considered.
+func (engine *EvalEngine) BestPlacement(
+	state *EvalState,
+	candidates []utilities.Set[types.Point],
+) (utilities.Set[types.Point], map[types.PlayerID]float64, error) {
+
+	eval := make(map[types.PlayerID]float64, len(state.players))
+	best, err := engine.searchPlacements(state, candidates, 0, eval)
+	if err != nil {
+		return nil, nil, err
+	}
+	if best == nil {
+		return nil, nil, errors.New("no placements")
+	}
+	return best, eval, nil
+}
+
 func (engine *EvalEngine) evaluateGameState(state *EvalState, curDepth int, curRes map[types.PlayerID]float64) error {
-	if curDepth >= int(engine.depth) || state.game.status.Has(COMPLETE) {
-		return nil
+	select {
+	case <-engine.chCancel:
+		return errEvalCancelled
+	default:
 	}
 
-	// iterate in order, starting with the current turn
-	for i := 0; i < len(state.players); i++ {
-		next := state.players[types.PlayerID((int(state.game.turn)+i)%len(state.players))+1]
-		if !next.status.Has(DISABLED) {
-			select {
-			case <-engine.chCancel:
-				return nil
-			default:
-				curRes[next.pid] += engine.evaluatePlayerPosition(state, next.pid, curDepth)
+	if curDepth >= int(engine.depth) || state.game.status.Has(COMPLETE) || state.game.turn == PID_NONE {
+		for pid, player := range state.players {
+			if player != nil {
+				curRes[pid] = engine.evaluatePlayerPosition(state, pid)
 			}
 		}
+		return nil
 	}
 
-	return nil
+	_, err := engine.searchPlacements(state, nil, curDepth, curRes)
+	return err
 }
 
-func (engine *EvalEngine) evaluatePlayerPosition(state *EvalState, pid types.PlayerID, curDepth int) float64 {
-	eval := 0.0
-	// territory := state.game.board.findTerritory(types.Owner(pid))
-	// corners := state.game.board.findCorners(territory, types.Owner(pid))
-	// placements := state.game.board.getPlacements(corners, types.Owner(pid), state.players[pid].pieces, false)
-
-	// numPlacements := 0
-	// playableArea := 0
-	// for p := placements; p != nil; p = p.Next {
-	// 	playableArea += len(p.Value)
-	// 	numPlacements++
-	// }
-
-	// fmt.Println("evaluating PID", pid)
-	// fmt.Println(len(territory), len(corners), numPlacements, playableArea)
-
-	// eval := (float64(len(territory))*WEIGHT_TERRITORY +
-	// 	float64(len(corners))*WEIGHT_CORNERS +
-	// 	float64(numPlacements)*WEIGHT_PLACEMENTS +
-	// 	float64(playableArea)*WEIGHT_OPEN_SPACE)
-
-	// for p := placements; p != nil; p = p.Next {
-	// 	gsCopy := state.game.Copy()
-	// 	playersCopy := make(map[types.PlayerID]*PlayerState, len(state.players))
-	// 	for pid, player := range state.players {
-	// 		playersCopy[pid] = player.Copy()
-	// 	}
-
-	// 	gsCopy.board.Place(utilities.NewSet(p.Value), pid)
-	// 	engine.evaluateGameState(&EvalState{gsCopy, playersCopy}, curDepth+1)
-	// }
-
-	return eval
+// searchPlacements tries every candidate for the player on turn and keeps the
+// line that leaves that player furthest ahead of their strongest opponent
+func (engine *EvalEngine) searchPlacements(
+	state *EvalState,
+	candidates []utilities.Set[types.Point],
+	curDepth int,
+	curRes map[types.PlayerID]float64,
+) (utilities.Set[types.Point], error) {
+
+	pid := state.game.turn
+	mover, ok := state.players[pid]
+	if !ok || mover == nil {
+		return nil, errors.New("invalid player id")
+	}
+
+	if candidates == nil {
+		candidates = state.game.board.findPlacements(pid, mover.pieces)
+	}
+
+	if len(candidates) == 0 {
+		// no moves left, this player sits out the rest of the line
+		next := state.Copy()
+		next.players[pid].status.Set(DISABLED)
+		next.advanceTurn()
+		return nil, engine.evaluateGameState(next, curDepth, curRes)
+	}
+
+	var best utilities.Set[types.Point]
+	var bestRes map[types.PlayerID]float64
+	bestScore := math.Inf(-1)
+
+	for _, candidate := range candidates {
+		next := state.Copy()
+		next.place(pid, candidate)
+
+		res := make(map[types.PlayerID]float64, len(state.players))
+		if err := engine.evaluateGameState(next, curDepth+1, res); err != nil {
+			return nil, err
+		}
+
+		if score := relativeScore(res, pid); score > bestScore {
+			bestScore = score
+			best = candidate
+			bestRes = res
+		}
+	}
+
+	for p, score := range bestRes {
+		curRes[p] = score
+	}
+	return best, nil
+}
+
+func (engine *EvalEngine) evaluatePlayerPosition(state *EvalState, pid types.PlayerID) float64 {
+	board := state.game.board
+	player := state.players[pid]
+
+	territory := board.findTerritory(types.Owner(pid))
+	eval := float64(len(territory)) * WEIGHT_TERRITORY
+
+	if player.status.Has(DISABLED) {
+		return eval // can't grow any further
+	}
+
+	corners := board.playableCorners(pid, territory)
+	placements := board.findPlacements(pid, player.pieces)
+
+	openSpace := utilities.NewSet([]types.Point{})
+	for _, placement := range placements {
+		for pt := range placement {
+			openSpace.Add(pt)
+		}
+	}
+
+	return eval +
+		float64(len(corners))*WEIGHT_CORNERS +
+		float64(len(placements))*WEIGHT_PLACEMENTS +
+		float64(openSpace.Size())*WEIGHT_OPEN_SPACE
+}
+
+// Score of a player relative to the best of their opponents
+func relativeScore(eval map[types.PlayerID]float64, pid types.PlayerID) float64 {
+	bestOther := math.Inf(-1)
+	for other, score := range eval {
+		if other != pid && score > bestOther {
+			bestOther = score
+		}
+	}
+	if math.IsInf(bestOther, -1) {
+		return eval[pid] // no opponents
+	}
+	return eval[pid] - bestOther
 }
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"math"
+	"testing"
+)
+
+var (
+	monomino = PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}}))
+	domino   = PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}))
+)
+
+// An open, square board with the given starting squares
+func newTestBoard(size uint, origins map[types.PlayerID]types.Point) *Board {
+	board := &Board{
+		layout:  make([][]types.Owner, size),
+		origins: make(map[types.PlayerID]types.Point, len(origins)),
+		maxX:    size,
+		maxY:    size,
+	}
+	for i := range board.layout {
+		board.layout[i] = make([]types.Owner, size)
+		for j := range board.layout[i] {
+			board.layout[i][j] = types.VACANT
+		}
+	}
+	for pid, pt := range origins {
+		board.occupy(pt, types.Owner(pid)|types.ORIGIN|types.VACANT)
+		board.origins[pid] = pt
+	}
+	return board
+}
+
+func newTestState(board *Board, pieces ...Piece) *EvalState {
+	set := PieceSet{}
+	for _, piece := range pieces {
+		set.Add(piece)
+	}
+	players := make(map[types.PlayerID]*PlayerState, len(board.origins))
+	for pid := range board.origins {
+		players[pid] = &PlayerState{
+			pid:    pid,
+			status: JOINED,
+			pieces: set.Copy(),
+		}
+	}
+	return &EvalState{&GameState{board, 1, 0}, players}
+}
+
+func expectScore(t *testing.T, territory, corners, placements, openSpace int, got float64) {
+	t.Helper()
+	expected := float64(territory)*WEIGHT_TERRITORY +
+		float64(corners)*WEIGHT_CORNERS +
+		float64(placements)*WEIGHT_PLACEMENTS +
+		float64(openSpace)*WEIGHT_OPEN_SPACE
+	if math.Abs(expected-got) > 1e-9 {
+		t.Errorf("expected score %v, got %v", expected, got)
+	}
+}
+
+func TestEvaluateOpeningPosition(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}})
+	state := newTestState(board, monomino)
+	engine := InitEvalEngine(0)
+
+	// only the origin is available, and only the monomino fits there
+	expectScore(t, 0, 1, 1, 1, engine.evaluatePlayerPosition(state, 1))
+}
+
+func TestEvaluateAfterPlacement(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}})
+	state := newTestState(board, domino)
+	board.Place(utilities.NewSet([]types.Point{{X: 0, Y: 0}}), 1)
+
+	// corner (1,1) takes the domino going right or up, covering 3 squares
+	expectScore(t, 1, 1, 2, 3, InitEvalEngine(0).evaluatePlayerPosition(state, 1))
+}
+
+func TestEvaluateBlockedPlayer(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}, 2: {X: 2, Y: 2}})
+	state := newTestState(board, monomino, domino)
+	board.Place(utilities.NewSet([]types.Point{{X: 0, Y: 0}}), 1)
+	board.Place(utilities.NewSet([]types.Point{{X: 1, Y: 1}}), 2)
+	state.players[1].pieces.Remove(monomino)
+
+	// player 1's only corner is taken by player 2
+	engine := InitEvalEngine(0)
+	expectScore(t, 1, 0, 0, 0, engine.evaluatePlayerPosition(state, 1))
+
+	// player 2 has 3 free corners, but only the monomino fits into any of them
+	expectScore(t, 1, 3, 3, 3, engine.evaluatePlayerPosition(state, 2))
+
+	state.players[2].status.Set(DISABLED)
+	expectScore(t, 1, 0, 0, 0, engine.evaluatePlayerPosition(state, 2))
+}
+
+func TestSearchOnePly(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}})
+	state := newTestState(board, monomino, domino)
+
+	best, eval, err := InitEvalEngine(1).BestPlacement(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// monomino first: territory 1, corner (1,1), 2 domino placements over 3 squares
+	monoFirst := 1*WEIGHT_TERRITORY + 1*WEIGHT_CORNERS + 2*WEIGHT_PLACEMENTS + 3*WEIGHT_OPEN_SPACE
+	// domino first: territory 2, a single corner for the monomino
+	dominoFirst := 2*WEIGHT_TERRITORY + 1*WEIGHT_CORNERS + 1*WEIGHT_PLACEMENTS + 1*WEIGHT_OPEN_SPACE
+
+	expectedSize := 1
+	expected := monoFirst
+	if dominoFirst > monoFirst {
+		expectedSize = 2
+		expected = dominoFirst
+	}
+
+	if best.Size() != expectedSize {
+		t.Errorf("expected a placement of size %v, got %v", expectedSize, best.Size())
+	}
+	if !best.Has(types.Point{X: 0, Y: 0}) {
+		t.Errorf("expected placement to cover the origin, got %v", best.ToSlice())
+	}
+	if math.Abs(eval[1]-expected) > 1e-9 {
+		t.Errorf("expected score %v, got %v", expected, eval[1])
+	}
+	if !board.vacant(types.Point{X: 0, Y: 0}) {
+		t.Errorf("search modified the original board")
+	}
+}
+
+func TestSearchTwoPly(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}})
+	state := newTestState(board, monomino, domino)
+
+	_, eval, err := InitEvalEngine(2).BestPlacement(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// either order ends with 3 squares placed and one corner left open
+	expectScore(t, 3, 1, 0, 0, eval[1])
+}
+
+func TestSearchRestrictedCandidates(t *testing.T) {
+	board := newTestBoard(3, map[types.PlayerID]types.Point{1: {X: 0, Y: 0}})
+	state := newTestState(board, monomino, domino)
+	only := utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 1, Y: 0}})
+
+	best, _, err := InitEvalEngine(1).BestPlacement(state, []utilities.Set[types.Point]{only})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !best.Is(only) {
+		t.Errorf("expected %v, got %v", only.ToSlice(), best.ToSlice())
+	}
+}
@@ -1,11 +1,14 @@
 package game
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"gobloks/internal/sockets"
 	"gobloks/internal/types"
 	"gobloks/internal/utilities"
+	"math"
+	"slices"
 	"sync"
 	"time"
 
@@ -41,17 +44,50 @@ type Game struct {
 	config         types.GameConfig
 	startingPieces PieceSet
 	socketManager  *sockets.SocketManager
+	createdAt      time.Time
 	lastActive     time.Time
 	evalEngine     *EvalEngine
 	state          *GameState
 	players        map[types.PlayerID]*Player
+	startingBoard  *Board
+	history        []types.Move
+	writer         *snapshotWriter // saves snapshots, once OnSnapshot is called
+	undo           *undoState
+	takeback       *takebackRequest
+	analysis       *types.Analysis
+	analyzing      bool
+	closed         bool
+	spectators     int // watch-only connections, up to MaxSpectators
+	passwordHash   []byte
 }
 
-func InitGame(gid types.GameID, config types.GameConfig) *Game {
+func InitGame(gid types.GameID, config types.GameConfig) (*Game, error) {
 
-	pieces, setPixels, err := GeneratePieceSet(config.BlockDegree) // TODO: cache
+	if len(config.Bots) >= int(config.Players) {
+		return nil, errors.New("at least one seat must be left for a human player")
+	}
+	for _, level := range config.Bots {
+		if !validBotLevel(level) {
+			return nil, fmt.Errorf("invalid bot level %d", level)
+		}
+	}
+
+	if config.Scoring > SCORING_OFFICIAL {
+		return nil, fmt.Errorf("invalid scoring mode %d", config.Scoring)
+	}
+	if err := validTeams(config); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := hashJoinPassword(config)
+	if err != nil {
+		return nil, err
+	}
+	config.Password = "" // only the hash is kept
+
+	pieces, setPixels, err := startingPieceSet(config)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	// playerStates := make(map[types.PlayerID]*PlayerState, config.Players)
@@ -71,35 +107,61 @@ func InitGame(gid types.GameID, config types.GameConfig) *Game {
 		players[pid] = nil
 	}
 
-	board, err := NewBoard(pids, setPixels, config.Density)
+	shape, err := boardShape(config)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	board, err := NewBoard(shape, pids, setPixels, config.Density)
+	if err != nil {
+		return nil, err
 	}
 
-	engine := InitEvalEngine(1)
-	go engine.Start()
-
-	go func() {
-		for eval := range engine.chResult {
-			fmt.Println("Eval result", eval)
-		}
-	}()
-
-	return &Game{
+	g := &Game{
 		gid:            gid,
 		lock:           &sync.Mutex{},
 		config:         config,
 		startingPieces: pieces,
 		socketManager:  sockets.InitSocketManager(len(pids)),
+		createdAt:      time.Now(),
 		lastActive:     time.Now(),
-		evalEngine:     engine,
+		evalEngine:     startEvalEngine(),
 		state: &GameState{
 			board,
 			pids[0],
 			0,
 		},
-		players: players,
+		players:       players,
+		startingBoard: board.Copy(),
+		history:       make([]types.Move, 0),
+		passwordHash:  passwordHash,
+	}
+
+	// bots take the last seats, so a human always has the first move
+	firstBotSeat := len(pids) - len(config.Bots)
+	for ii, level := range config.Bots {
+		pid := pids[firstBotSeat+ii]
+		bot := g.seatPlayer(pid, botName(pid, level), botColor(ii))
+		bot.state.status.Set(CONNECTED | BOT)
+		bot.bot = &Bot{level: level}
 	}
+
+	return g, nil
+}
+
+func startEvalEngine() *EvalEngine {
+	engine := InitEvalEngine(1)
+	go engine.Start()
+
+	go func() {
+		for eval := range engine.chResult {
+			fmt.Println("Eval result", eval)
+		}
+	}()
+	return engine
+}
+
+func (g *Game) ID() types.GameID {
+	return g.gid
 }
 
 func (g *Game) GetPlayers() map[types.PlayerID]*Player {
@@ -107,6 +169,9 @@ func (g *Game) GetPlayers() map[types.PlayerID]*Player {
 }
 
 func (g *Game) IsStale() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
 	var cleanupAfter time.Duration
 	if g.config.TimeControl == 0 {
 		// Cleanup untimed games after a week of inactivity
@@ -118,10 +183,40 @@ func (g *Game) IsStale() bool {
 	return time.Since(g.lastActive) > cleanupAfter
 }
 
+// Shut the game down for good. Its timers, bots and engine stop, its
+// connections close, and nothing it does from now on is persisted.
+func (g *Game) Close() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.closed {
+		return
+	}
+	g.closed = true
+	if g.writer != nil {
+		g.writer.stop()
+	}
+
+	for _, player := range g.players {
+		if player == nil {
+			continue
+		}
+		if player.connectionTimer != nil {
+			player.connectionTimer.Pause()
+		}
+		player.playerTimer.Pause()
+		player.socket = nil // so closing it doesn't start a reconnect timer
+	}
+	g.socketManager.CloseAll()
+	if !g.state.status.Has(COMPLETE) {
+		g.evalEngine.Stop() // already stopped when the game ended
+	}
+}
+
 func (g *Game) nextTurn() {
 	for _, player := range g.players {
-		if player != nil && !player.state.status.Has(DISABLED) && player.possiblePlacements.Next == nil {
+		if player != nil && !player.state.status.Has(DISABLED) && player.possiblePlacements.Empty() {
 			player.state.status.Set(DISABLED)
+			g.recordMove(MOVE_PASS, player, nil)
 		}
 	}
 
@@ -139,81 +234,42 @@ func (g *Game) nextTurn() {
 }
 
 func (g *Game) updateValidPlacements(player *Player, placement utilities.Set[types.Point]) {
-	var wg sync.WaitGroup
-
-	updatePlayerPlacements := func(p *Player) {
-		defer wg.Done()
-		prev := p.possiblePlacements
-		for plc := p.possiblePlacements.Next; plc != nil; plc = plc.Next {
-			removed := false
-			for pt := range placement {
-				if plc.Value.Has(pt) {
-					prev.Next = plc.Next // remove this from the list- placement is no longer valid
-					removed = true
-					break
-				}
-			}
-			if !removed {
-				prev = plc // else prev stays the same
-			}
-		}
-	}
+	covered := placement.ToSlice()
 
-	// TODO: use goroutines to speed this up
+	// nobody can play over the new piece
 	for _, p := range g.players {
-		if p == nil {
-			continue
+		if p != nil {
+			p.possiblePlacements.RemoveCovering(covered...)
 		}
-		wg.Add(1)
-		go updatePlayerPlacements(p)
 	}
-	// NOTE: could technically continue, we only need to wait for the player who just placed
-	//       this wouldn't be too much of an optimization though
-	wg.Wait()
-
-	asPiece := PieceFromPoints(placement)
-	prev := player.possiblePlacements
-	for plc := player.possiblePlacements.Next; plc != nil; plc = plc.Next {
 
-		if PieceFromPoints(plc.Value).IsSame(asPiece) {
-			prev.Next = plc.Next // remove this from the list- placement is no longer valid
-			continue
-		}
-		removed := false
-		for pt := range placement {
-			if plc.Value.Has(pt) ||
-				plc.Value.Has(pt.GetAdjacent(types.UP)) ||
-				plc.Value.Has(pt.GetAdjacent(types.DOWN)) ||
-				plc.Value.Has(pt.GetAdjacent(types.RIGHT)) ||
-				plc.Value.Has(pt.GetAdjacent(types.LEFT)) {
-				prev.Next = plc.Next // remove this from the list- placement is no longer valid
-				removed = true
-				break
-			}
-		}
-		if !removed {
-			prev = plc
+	// the player can't reuse the piece, or play alongside it
+	player.possiblePlacements.RemovePiece(PieceFromPoints(placement))
+	sides := make([]types.Point, 0, len(covered)*4)
+	for _, pt := range covered {
+		for _, dir := range []types.Direction{types.UP, types.DOWN, types.LEFT, types.RIGHT} {
+			sides = append(sides, pt.GetAdjacent(dir))
 		}
 	}
+	player.possiblePlacements.RemoveCovering(sides...)
 
 	newCorners := g.state.board.findCorners(
-		placement.ToSlice(),
+		covered,
 		types.Owner(player.state.pid),
 	)
-
-	last := prev
 	for _, pt := range newCorners {
-		last.Next = g.state.board.getPlacementsAtPoint(
+		for _, plc := range g.state.board.getPlacementsAtPoint(
 			pt,
 			types.Owner(player.state.pid),
 			player.state.pieces.Copy(),
-		).Next
-		for ; last.Next != nil; last = last.Next {
+		) {
+			player.possiblePlacements.Add(plc)
 		}
 	}
 }
 
 func (g *Game) updateGameState(player *Player) bool {
+	defer g.saveSnapshot()   // persist the new state
 	defer g.sendPlayerList() // broadcast updated player list
 	defer g.sendGameStatus() // broadcast updated game status
 
@@ -227,13 +283,19 @@ func (g *Game) updateGameState(player *Player) bool {
 			nextPlayer.playerTimer.Start()
 		}
 	}
+	g.resolveTakeback()
+	g.scheduleBots()
 	return false // game not over
 }
 
 func (g *Game) endGame() {
 	winners := g.determineWinners()
 	winString := "Game over! "
-	if len(winners) > 1 {
+	if g.teamsEnabled() {
+		winString = g.settleTeams()
+	} else if len(winners) == 0 {
+		winString += "Everyone resigned."
+	} else if len(winners) > 1 {
 		for i := 0; i < len(winners)-1; i++ {
 			winners[i].state.status.Set(DRAWN)
 
@@ -244,6 +306,7 @@ func (g *Game) endGame() {
 				winString += " "
 			}
 		}
+		winners[len(winners)-1].state.status.Set(DRAWN)
 		winString += "and " + winners[len(winners)-1].name + " tied!"
 	} else {
 		winners[0].state.status.Set(WINNER)
@@ -252,6 +315,7 @@ func (g *Game) endGame() {
 	g.sendGameMessage(winString)
 	g.state.status.Set(COMPLETE)
 	g.evalEngine.Stop()
+	g.startAnalysis()
 
 	// Stop all player timers
 	for _, player := range g.players {
@@ -266,22 +330,80 @@ func (g *Game) endGame() {
 	}
 }
 
-func (g *Game) receiveMessages(player *Player) {
+func (g *Game) receiveMessages(player *Player, conn *sockets.Connection) {
 	for {
 		var inMsg types.SocketData
-		err := g.socketManager.Recv(player.socket, &inMsg)
+		err := g.socketManager.Recv(conn, &inMsg)
 		if err != nil {
 			fmt.Println(err)
 			break
 		}
-		if inMsg.Type == sockets.CHAT_MESSAGE {
-			g.socketManager.Broadcast(&inMsg)
+		switch inMsg.Type {
+		case sockets.CHAT_MESSAGE:
+			var chat types.ChatMessage
+			if err := sockets.DecodeData(&inMsg, &chat); err != nil {
+				continue
+			}
+			// players can only speak for themselves
+			chat.Origin = types.Owner(player.state.pid)
+			g.socketManager.Broadcast(&types.SocketData{Type: sockets.CHAT_MESSAGE, Data: &chat})
+		case sockets.REPLAY:
+			// no password check, the seat was only taken after one
+			g.lock.Lock()
+			g.sendReplay(player.socket)
+			g.lock.Unlock()
+		case sockets.PASS:
+			if err := g.Pass(player.state.pid); err != nil {
+				g.lock.Lock()
+				g.sendPrivateMessage(player, err.Error())
+				g.lock.Unlock()
+			}
+		case sockets.RESIGN:
+			if err := g.Resign(player.state.pid); err != nil {
+				g.lock.Lock()
+				g.sendPrivateMessage(player, err.Error())
+				g.lock.Unlock()
+			}
+		case sockets.TAKEBACK_REQUEST:
+			g.lock.Lock()
+			if err := g.requestTakeback(player); err != nil {
+				g.sendPrivateMessage(player, err.Error())
+			}
+			g.lock.Unlock()
+		case sockets.LEGAL_MOVES:
+			var query types.MovesQuery
+			if err := sockets.DecodeData(&inMsg, &query); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			moves, err := g.LegalMoves(player.state.pid, query)
+			g.lock.Lock()
+			if err != nil {
+				g.sendPrivateMessage(player, err.Error())
+			} else {
+				g.socketManager.Send(player.socket, &types.SocketData{Type: sockets.LEGAL_MOVES, Data: moves})
+			}
+			g.lock.Unlock()
+		case sockets.TAKEBACK_RESPONSE:
+			var response types.TakebackResponse
+			if err := sockets.DecodeData(&inMsg, &response); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			g.lock.Lock()
+			if err := g.respondTakeback(player, response.Accept); err != nil {
+				g.sendPrivateMessage(player, err.Error())
+			}
+			g.lock.Unlock()
 		}
 	}
 
 	// handle socket disconnection type events
 	g.lock.Lock()
 	defer g.lock.Unlock()
+	if player.socket != conn {
+		return // replaced by a newer connection, which is still live
+	}
 	g.socketManager.Disconnect(player.socket)
 	player.socket = nil
 	player.state.status.Clear(CONNECTED)
@@ -290,11 +412,21 @@ func (g *Game) receiveMessages(player *Player) {
 	g.sendPlayerList()
 	g.sendGameStatus()
 
-	player.connectionTimer = utilities.InitTimer(15000, 0, func(...any) {
+	g.startConnectionTimer(player)
+}
+
+// How long a disconnected player has to come back before they're dropped
+var ReconnectGrace = 15 * time.Second
+
+// Disable the player if they don't reconnect in time
+func (g *Game) startConnectionTimer(player *Player) {
+	player.connectionTimer = utilities.InitTimer(uint(ReconnectGrace.Milliseconds()), 0, func(...any) {
 		g.lock.Lock()
 		defer g.lock.Unlock()
 		player.state.status.Set(DISABLED) // Remove player from active set
 		player.playerTimer.Pause()        // stop timer if applicable
+		player.revokeTokens()             // the seat is gone, so are its tokens
+		g.recordMove(MOVE_DISCONNECT, player, nil)
 		g.updateGameState(player)
 		g.sendGameMessage(fmt.Sprintf("%s has left the game", player.name))
 	})
@@ -311,7 +443,50 @@ func (g *Game) sendGameMessage(msg string) {
 	})
 }
 
+func (g *Game) sendPrivateState(player *Player) {
+	if player.socket == nil {
+		return
+	}
+
+	var playerPieces []types.PublicPiece
+
+	for piece := range player.state.pieces {
+		playerPieces = append(playerPieces, types.PublicPiece{
+			Hash: piece.Hash(),
+			Body: piece.ToPoints().ToSlice(),
+		})
+	}
+
+	g.socketManager.Send(
+		player.socket,
+		&types.SocketData{
+			Type: sockets.PRIVATE_GAME_STATE,
+			Data: &types.PrivateGameState{PID: player.state.pid, Pieces: playerPieces, Hints: player.hints},
+		},
+	)
+}
+
+func (g *Game) sendPrivateMessage(player *Player, msg string) {
+	if player.socket == nil {
+		return
+	}
+	g.socketManager.Send(player.socket, &types.SocketData{
+		Type: sockets.CHAT_MESSAGE,
+		Data: &types.ChatMessage{
+			Origin:  types.RESERVED,
+			Message: msg,
+		},
+	})
+}
+
 func (g *Game) sendPlayerList() {
+	g.socketManager.Broadcast(&types.SocketData{
+		Type: sockets.PLAYER_UPDATE,
+		Data: g.playerList(),
+	})
+}
+
+func (g *Game) playerList() []types.PlayerConfig {
 	players := make([]types.PlayerConfig, 0, len(g.players))
 	for pid, player := range g.players {
 		if player != nil {
@@ -321,32 +496,61 @@ func (g *Game) sendPlayerList() {
 				Color:  player.color,
 				Status: player.state.status,
 				Time:   player.playerTimer.TimeLeftMs(),
+				Team:   g.teamOf(pid),
 			})
 		}
 	}
-
-	g.socketManager.Broadcast(&types.SocketData{
-		Type: sockets.PLAYER_UPDATE,
-		Data: players,
+	// list teammates together
+	slices.SortFunc(players, func(a, b types.PlayerConfig) int {
+		if n := cmp.Compare(a.Team, b.Team); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.PID, b.PID)
 	})
+	return players
 }
 
 func (g *Game) sendGameStatus() {
 	g.socketManager.Broadcast(&types.SocketData{
 		Type: sockets.GAME_STATUS,
-		Data: &types.PublicGameState{Turn: g.state.turn, Status: g.state.status},
+		Data: g.publicState(),
 	})
 }
 
+func (g *Game) publicState() *types.PublicGameState {
+	status := &types.PublicGameState{Turn: g.state.turn, Status: g.state.status}
+	if g.state.status.Has(COMPLETE) {
+		status.Scores = g.scores()
+		if g.teamsEnabled() {
+			status.TeamScores = g.teamScores(status.Scores)
+		}
+	}
+	return status
+}
+
+// Snapshot of the game for the eval engine to search
+func (g *Game) evalState() *EvalState {
+	players := make(map[types.PlayerID]*PlayerState, len(g.players))
+	for pid, player := range g.players {
+		if player != nil {
+			players[pid] = player.state.Copy()
+		} else {
+			players[pid] = nil
+		}
+	}
+	return &EvalState{g.state.Copy(), players}
+}
+
 func (g *Game) getPlayer(pid types.PlayerID) (*Player, error) {
 	player, ok := g.players[pid]
-	if !ok {
+	if !ok || player == nil { // empty seats have no player yet
 		return nil, errors.New("invalid player id")
 	}
 	return player, nil
 }
 
-func (g *Game) AddPlayer(name string, color uint) (types.PlayerID, error) {
+// Seat a new player, on the given team if teams are enabled and team is non-zero
+func (g *Game) AddPlayer(name string, color uint, team uint) (types.PlayerID, error) {
 	/* Assign the new player a PID, if there is one available */
 	g.lock.Lock()
 	defer g.lock.Unlock()
@@ -355,46 +559,60 @@ func (g *Game) AddPlayer(name string, color uint) (types.PlayerID, error) {
 		return 0, errors.New("game full")
 	}
 
-	var ii int
-	for ii = 1; ii <= len(g.players); ii++ {
-		pid := types.PlayerID(ii)
-		if g.players[pid] == nil {
-			g.players[pid] = &Player{
-				name:  name,
-				color: color,
-				state: &PlayerState{
-					pid:    pid,
-					status: JOINED,
-					pieces: g.startingPieces.Copy(),
-				},
-				socket: nil,
-				playerTimer: utilities.InitTimer(
-					g.config.TimeControl*1000,
-					g.config.TimeBonus*1000,
-					g.handleTimeout,
-					pid,
-				),
-				connectionTimer: nil,
-				possiblePlacements: g.state.board.getPlacementsAtPoint(
-					g.state.board.getOrigin(pid),
-					types.Owner(pid),
-					g.startingPieces.Copy(),
-				),
-				hints: g.config.Hints,
-			}
-			break
-		}
+	pid, err := g.openSeat(team)
+	if err != nil {
+		return 0, err
 	}
+	g.seatPlayer(pid, name, color)
 
-	fmt.Println("Added player ", ii)
+	fmt.Println("Added player ", pid)
 	g.lastActive = time.Now()
 
-	if ii == len(g.players) {
+	if g.seatsFilled() {
 		fmt.Println("Game is full")
 		g.state.status.Set(FULL)
+		g.scheduleBots()
 	}
+	g.saveSnapshot()
+
+	return pid, nil
+}
 
-	return types.PlayerID(ii), nil
+func (g *Game) seatPlayer(pid types.PlayerID, name string, color uint) *Player {
+	player := &Player{
+		name:  name,
+		color: color,
+		state: &PlayerState{
+			pid:    pid,
+			status: JOINED,
+			pieces: g.startingPieces.Copy(),
+		},
+		socket: nil,
+		playerTimer: utilities.InitTimer(
+			g.config.TimeControl*1000,
+			g.config.TimeBonus*1000,
+			g.handleTimeout,
+			pid,
+		),
+		connectionTimer: nil,
+		possiblePlacements: NewPlacementIndex(g.state.board.getPlacementsAtPoint(
+			g.state.board.getOrigin(pid),
+			types.Owner(pid),
+			g.startingPieces.Copy(),
+		)...),
+		hints: g.config.Hints,
+	}
+	g.players[pid] = player
+	return player
+}
+
+func (g *Game) seatsFilled() bool {
+	for _, player := range g.players {
+		if player == nil {
+			return false
+		}
+	}
+	return true
 }
 
 func (g *Game) ConnectSocket(socket *websocket.Conn, pid types.PlayerID) error {
@@ -409,12 +627,19 @@ func (g *Game) ConnectSocket(socket *websocket.Conn, pid types.PlayerID) error {
 	if !player.state.status.Has(JOINED) {
 		return errors.New("invalid player status")
 	}
+	if g.closed {
+		return errors.New("game has been closed")
+	}
 
+	if player.socket != nil {
+		// the seat has moved to a new connection, so drop the old one
+		g.socketManager.Close(player.socket)
+	}
 	player.socket = g.socketManager.Connect(socket)
 	player.state.status.Set(CONNECTED)
 
 	// begin receiving messages on this socket
-	go g.receiveMessages(player)
+	go g.receiveMessages(player, player.socket)
 
 	if player.connectionTimer != nil {
 		player.connectionTimer.Pause()
@@ -422,26 +647,11 @@ func (g *Game) ConnectSocket(socket *websocket.Conn, pid types.PlayerID) error {
 
 	fmt.Println("Connected player ", pid)
 
-	var playerPieces []types.PublicPiece
-
-	for piece := range player.state.pieces {
-		playerPieces = append(playerPieces, types.PublicPiece{
-			Hash: piece.Hash(),
-			Body: piece.ToPoints().ToSlice(),
-		})
-	}
-
 	// Send all players the current player list and status to sync up
 	g.sendPlayerList()
 	g.sendGameStatus()
 	// Send the player their PID, pieces, and current board on connection
-	g.socketManager.Send(
-		player.socket,
-		&types.SocketData{
-			Type: sockets.PRIVATE_GAME_STATE,
-			Data: &types.PrivateGameState{PID: pid, Pieces: playerPieces, Hints: player.hints},
-		},
-	)
+	g.sendPrivateState(player)
 	g.socketManager.Send(player.socket, &types.SocketData{Type: sockets.BOARD_STATE, Data: g.state.board.GetRaw()})
 	g.sendGameMessage(fmt.Sprintf("%s has joined the game", player.name))
 
@@ -462,24 +672,14 @@ func (g *Game) PlacePiece(pid types.PlayerID, placement types.Placement) error {
 		return err
 	}
 
-	// ptSet := utilities.NewSet(placement)
-	// piece := PieceFromPoints(ptSet)
-	// if !player.state.pieces.Has(piece) {
-	// 	return errors.New("player does not have this piece")
-	// }
-
 	internalPlace := utilities.NewSet(placement)
-	hasPlacement := false
-	for p := player.possiblePlacements.Next; p != nil; p = p.Next {
-		if p.Value.Is(internalPlace) {
-			hasPlacement = true
-			break
-		}
-	}
-	if !hasPlacement {
-		return errors.New("invalid placement")
+	if !player.possiblePlacements.Has(internalPlace) {
+		return g.rejectPlacement(player, placement)
 	}
 
+	undo := g.captureUndo(player, internalPlace)
+	g.undo = nil // the previous placement can no longer be taken back
+
 	_, err = g.state.board.Place(internalPlace, pid)
 	if err != nil {
 		return err
@@ -498,12 +698,16 @@ func (g *Game) PlacePiece(pid types.PlayerID, placement types.Placement) error {
 	})
 
 	player.playerTimer.Pause() // successfully placed piece, pause timer
+	g.recordMove(MOVE_PLACE, player, placement)
 
 	player.state.pieces.Remove(PieceFromPoints(internalPlace))
 
 	g.updateValidPlacements(player, internalPlace)
 
-	g.updateGameState(player)
+	gameOver := g.updateGameState(player)
+	if !gameOver {
+		g.undo = undo // this placement may be taken back
+	}
 	// if !gameOver {
 	// 	playerStates := make(map[types.PlayerID]*PlayerState, len(g.players))
 	// 	for pid, player := range g.players {
@@ -517,48 +721,17 @@ func (g *Game) PlacePiece(pid types.PlayerID, placement types.Placement) error {
 	return nil
 }
 
-func (g *Game) GetHint(pid types.PlayerID) (types.Point, error) {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	player, err := g.getPlayer(pid)
-	if err != nil {
-		return types.Point{}, err
-	}
-
-	_, err = g.playerActionValid(player)
-	if err != nil {
-		return types.Point{}, err
-	}
-
-	if player.hints == 0 {
-		return types.Point{}, errors.New("no more hints")
-	}
-
-	player.hints -= 1
-
-	// TODO: don't return the same hint twice in a row
-	if player.possiblePlacements.Next != nil {
-		for pt := range player.possiblePlacements.Next.Value {
-			if g.state.board.HasCorner(pt, types.Owner(pid)) {
-				return pt, nil
-			}
-		}
-	}
-	return types.Point{}, errors.New("no corners")
-}
-
 func (g *Game) playerActionValid(player *Player) (bool, error) {
 	if g.config.TurnBased && g.state.turn != player.state.pid {
-		return false, errors.New("not your turn")
+		return false, gameError(ERR_NOT_YOUR_TURN, "not your turn")
 	}
 
 	if player.state.status.Has(TIMED_OUT | DISABLED) {
-		return false, errors.New("player inactive")
+		return false, gameError(ERR_PLAYER_INACTIVE, "player inactive")
 	}
 
 	if !g.state.status.Has(FULL) {
-		return false, errors.New("waiting for all players")
+		return false, gameError(ERR_WAITING_FOR_PLAYERS, "waiting for all players")
 	}
 
 	return true, nil
@@ -566,26 +739,19 @@ func (g *Game) playerActionValid(player *Player) (bool, error) {
 
 func (g *Game) determineWinners() []*Player {
 	winners := make([]*Player, 0, len(g.players))
-	scores := make(map[*Player]int, len(g.players))
-	minScore := 0xffffffff
+	scores := g.scores()
+	maxScore := math.MinInt
 
-	for _, player := range g.players {
-		if player != nil {
-			score := 0
-			for piece := range player.state.pieces {
-				score += int(piece.Size())
-			}
-			scores[player] = score
-			if score <= minScore {
-				minScore = score
-			}
+	for pid, score := range scores {
+		if g.players[pid].contending() && score >= maxScore {
+			maxScore = score
 		}
 	}
 
-	for player, score := range scores {
-		fmt.Println(player.name, score)
-		if score == minScore {
-			winners = append(winners, player)
+	for pid, score := range scores {
+		fmt.Println(g.players[pid].name, score)
+		if g.players[pid].contending() && score == maxScore {
+			winners = append(winners, g.players[pid])
 		}
 	}
 	return winners
@@ -601,5 +767,6 @@ func (g *Game) handleTimeout(args ...any) {
 	if player.connectionTimer != nil {
 		player.connectionTimer.Pause()
 	}
+	g.recordMove(MOVE_TIMEOUT, player, nil)
 	g.updateGameState(player)
 }
@@ -0,0 +1,240 @@
+package game
+
+import (
+	"cmp"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"slices"
+)
+
+// Hint levels, from least to most helpful
+const (
+	HINT_CORNER    types.HintLevel = iota + 1 // a corner worth playing from
+	HINT_PIECE                                // a piece worth playing
+	HINT_PLACEMENT                            // the placement the eval engine likes best
+)
+
+var defaultHintCosts = map[types.HintLevel]uint{
+	HINT_CORNER:    1,
+	HINT_PIECE:     2,
+	HINT_PLACEMENT: 3,
+}
+
+// Hints already given to a player, so they aren't given again
+type hintLog struct {
+	corners    utilities.Set[types.Point]
+	pieces     utilities.Set[uint64]
+	placements utilities.Set[placementKey]
+}
+
+func (p *Player) givenHints() *hintLog {
+	if p.hintLog == nil {
+		p.hintLog = &hintLog{
+			corners:    utilities.NewSet([]types.Point{}),
+			pieces:     utilities.NewSet([]uint64{}),
+			placements: utilities.NewSet([]placementKey{}),
+		}
+	}
+	return p.hintLog
+}
+
+func (given *hintLog) snapshot() *HintSnapshot {
+	snap := &HintSnapshot{
+		Corners:    given.corners.ToSlice(),
+		Pieces:     given.pieces.ToSlice(),
+		Placements: make([]types.Placement, 0, given.placements.Size()),
+	}
+	for key := range given.placements {
+		snap.Placements = append(snap.Placements, key.points().ToSlice())
+	}
+	return snap
+}
+
+func restoreHintLog(snap *HintSnapshot) *hintLog {
+	given := &hintLog{
+		corners:    utilities.NewSet(snap.Corners),
+		pieces:     utilities.NewSet(snap.Pieces),
+		placements: utilities.NewSet([]placementKey{}),
+	}
+	for _, placement := range snap.Placements {
+		given.placements.Add(keyOf(utilities.NewSet(placement)))
+	}
+	return given
+}
+
+func (g *Game) hintCost(level types.HintLevel) uint {
+	var cost uint
+	switch level {
+	case HINT_CORNER:
+		cost = g.config.HintCosts.Corner
+	case HINT_PIECE:
+		cost = g.config.HintCosts.Piece
+	case HINT_PLACEMENT:
+		cost = g.config.HintCosts.Placement
+	}
+	if cost == 0 {
+		cost = defaultHintCosts[level]
+	}
+	return cost
+}
+
+// Spend hints for advice the player hasn't been given before
+func (g *Game) GetHint(pid types.PlayerID, level types.HintLevel) (*types.Hint, error) {
+	if level == HINT_PLACEMENT {
+		return g.hintPlacement(pid)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.checkHint(pid, level)
+	if err != nil {
+		return nil, err
+	}
+
+	hint := &types.Hint{Level: level}
+	given := player.givenHints()
+
+	switch level {
+	case HINT_CORNER:
+		corner, ok := g.hintCorner(player)
+		if !ok {
+			return nil, gameError(ERR_NO_NEW_HINTS, "no new corners to suggest")
+		}
+		given.corners.Add(corner)
+		hint.Corner = &corner
+	case HINT_PIECE:
+		piece, ok := g.hintPiece(player)
+		if !ok {
+			return nil, gameError(ERR_NO_NEW_HINTS, "no new pieces to suggest")
+		}
+		given.pieces.Add(piece.Hash())
+		hint.Piece = &types.PublicPiece{Hash: piece.Hash(), Body: piece.ToPoints().ToSlice()}
+	}
+
+	return g.chargeHint(player, hint), nil
+}
+
+// Suggest the placement the eval engine likes best. Searching can take a
+// while, so it runs without the game lock and the game carries on meanwhile.
+func (g *Game) hintPlacement(pid types.PlayerID) (*types.Hint, error) {
+	state, candidates, err := g.placementHintSearch(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	best, _, err := g.evalEngine.BestPlacement(state, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	// the game may have moved on while the engine was thinking
+	player, err := g.checkHint(pid, HINT_PLACEMENT)
+	if err != nil {
+		return nil, err
+	}
+	given := player.givenHints()
+	if !player.possiblePlacements.Has(best) || given.placements.Has(keyOf(best)) {
+		return nil, gameError(ERR_NO_NEW_HINTS, "the position changed, try again")
+	}
+	given.placements.Add(keyOf(best))
+	return g.chargeHint(player, &types.Hint{Level: HINT_PLACEMENT, Placement: best.ToSlice()}), nil
+}
+
+// What the engine needs to search for a placement hint
+func (g *Game) placementHintSearch(pid types.PlayerID) (*EvalState, []utilities.Set[types.Point], error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.checkHint(pid, HINT_PLACEMENT)
+	if err != nil {
+		return nil, nil, err
+	}
+	given := player.givenHints()
+	candidates := make([]utilities.Set[types.Point], 0)
+	for _, plc := range player.possiblePlacements.All() {
+		if !given.placements.Has(keyOf(plc)) {
+			candidates = append(candidates, plc)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, gameError(ERR_NO_NEW_HINTS, "no new placements to suggest")
+	}
+
+	state := g.evalState()
+	state.game.turn = pid // search from the player's point of view
+	return state, largestPlacements(candidates, botSearchCandidates), nil
+}
+
+// Take the hint's cost from the player and tell them. Must be called with the game lock held.
+func (g *Game) chargeHint(player *Player, hint *types.Hint) *types.Hint {
+	player.hints -= g.hintCost(hint.Level)
+	hint.Remaining = player.hints
+	g.sendPrivateState(player)
+	g.saveSnapshot()
+	return hint
+}
+
+// Check the player may take a hint of the given level. Must be called with the game lock held.
+func (g *Game) checkHint(pid types.PlayerID, level types.HintLevel) (*Player, error) {
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := defaultHintCosts[level]; !ok {
+		return nil, gameError(ERR_INVALID_HINT, "invalid hint level %d", level)
+	}
+	if _, err = g.playerActionValid(player); err != nil {
+		return nil, err
+	}
+	if player.hints < g.hintCost(level) {
+		return nil, gameError(ERR_NO_HINTS_LEFT, "not enough hints left")
+	}
+	return player, nil
+}
+
+// The corner with the most placements through it that hasn't been suggested yet
+func (g *Game) hintCorner(player *Player) (types.Point, bool) {
+	territory := g.state.board.findTerritory(types.Owner(player.state.pid))
+	corners := g.state.board.playableCorners(player.state.pid, territory)
+	slices.SortFunc(corners, comparePoints)
+
+	var best types.Point
+	bestCount := 0
+	for _, corner := range corners {
+		if player.givenHints().corners.Has(corner) {
+			continue
+		}
+		if count := len(player.possiblePlacements.AtCell(corner)); count > bestCount {
+			best, bestCount = corner, count
+		}
+	}
+	return best, bestCount > 0
+}
+
+// The largest playable piece that hasn't been suggested yet
+func (g *Game) hintPiece(player *Player) (Piece, bool) {
+	pieces := make([]Piece, 0, player.state.pieces.Size())
+	for piece := range player.state.pieces {
+		pieces = append(pieces, piece)
+	}
+	slices.SortFunc(pieces, func(a, b Piece) int {
+		if c := cmp.Compare(b.Size(), a.Size()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Hash(), b.Hash())
+	})
+
+	for _, piece := range pieces {
+		if player.givenHints().pieces.Has(piece.Hash()) {
+			continue
+		}
+		if len(player.possiblePlacements.ForPiece(piece)) > 0 {
+			return piece, true
+		}
+	}
+	return Piece{}, false
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"encoding/json"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"testing"
+)
+
+func TestHintLevels(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Hints: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xffffff, 0)
+	g.AddPlayer("two", 0xffffff, 0)
+
+	hint, err := g.GetHint(p1, HINT_CORNER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint.Corner == nil || *hint.Corner != g.state.board.origins[p1] || hint.Remaining != 5 {
+		t.Errorf("expected the origin for 1 hint, got %+v", hint)
+	}
+	_, err = g.GetHint(p1, HINT_CORNER)
+	expectCode(t, err, ERR_NO_NEW_HINTS)
+	if g.players[p1].hints != 5 {
+		t.Errorf("a repeated hint shouldn't be charged")
+	}
+
+	hint, err = g.GetHint(p1, HINT_PIECE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint.Piece == nil || len(hint.Piece.Body) != 3 || hint.Remaining != 3 {
+		t.Errorf("expected a largest piece for 2 hints, got %+v", hint)
+	}
+
+	hint, err = g.GetHint(p1, HINT_PLACEMENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.players[p1].possiblePlacements.Has(utilities.NewSet(hint.Placement)) || hint.Remaining != 0 {
+		t.Errorf("expected a legal placement for 3 hints, got %+v", hint)
+	}
+
+	_, err = g.GetHint(p1, HINT_CORNER)
+	expectCode(t, err, ERR_NO_HINTS_LEFT)
+	_, err = g.GetHint(p1, 9)
+	expectCode(t, err, ERR_INVALID_HINT)
+}
+
+func TestHintCosts(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{
+		Players: 1, BlockDegree: 4, Density: 1, Hints: 3,
+		HintCosts: types.HintCosts{Corner: 4, Placement: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := g.AddPlayer("solo", 0xffffff, 0)
+
+	_, err = g.GetHint(pid, HINT_CORNER)
+	expectCode(t, err, ERR_NO_HINTS_LEFT)
+
+	hint, err := g.GetHint(pid, HINT_PLACEMENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint.Remaining != 2 {
+		t.Errorf("expected a placement hint to cost 1, %d left", hint.Remaining)
+	}
+	second, err := g.GetHint(pid, HINT_PLACEMENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utilities.NewSet(second.Placement).Is(utilities.NewSet(hint.Placement)) {
+		t.Errorf("the same placement was suggested twice")
+	}
+}
+
+func TestHintsGivenSurviveRestore(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 1, BlockDegree: 3, Density: 0.5, Hints: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := g.AddPlayer("solo", 0xffffff, 0)
+	if _, err := g.GetHint(pid, HINT_CORNER); err != nil {
+		t.Fatal(err)
+	}
+	piece, err := g.GetHint(pid, HINT_PIECE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	placement, err := g.GetHint(pid, HINT_PLACEMENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(g.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreGame(&snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	given := restored.players[pid].givenHints()
+	if !given.corners.Has(g.state.board.origins[pid]) || !given.pieces.Has(piece.Piece.Hash) {
+		t.Errorf("corner and piece hints lost in restore")
+	}
+	if !given.placements.Has(keyOf(utilities.NewSet(placement.Placement))) {
+		t.Errorf("placement hint lost in restore")
+	}
+	_, err = restored.GetHint(pid, HINT_CORNER)
+	expectCode(t, err, ERR_NO_NEW_HINTS)
+}
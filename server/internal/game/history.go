@@ -0,0 +1,52 @@
+package game
+
+import (
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+	"time"
+)
+
+// Move types recorded in the game history
+const (
+	MOVE_PLACE      types.MoveType = iota // placed a piece
+	MOVE_PASS                             // passed, or ran out of placements
+	MOVE_TIMEOUT                          // ran out of time
+	MOVE_DISCONNECT                       // disabled after disconnecting
+	MOVE_TAKEBACK                         // previous placement taken back
+	MOVE_RESIGN                           // resigned
+)
+
+// Append a move to the history. Must be called with the game lock held.
+func (g *Game) recordMove(moveType types.MoveType, player *Player, placement types.Placement) {
+	g.history = append(g.history, types.Move{
+		Seq:       uint(len(g.history)),
+		Type:      moveType,
+		PID:       player.state.pid,
+		Placement: placement,
+		Time:      player.playerTimer.TimeLeftMs(),
+		Timestamp: time.Now(),
+	})
+	if moveType != MOVE_PLACE {
+		g.undo = nil // only the latest placement can be taken back
+	}
+}
+
+func (g *Game) Replay() *types.Replay {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.replay()
+}
+
+func (g *Game) replay() *types.Replay {
+	moves := make([]types.Move, len(g.history))
+	copy(moves, g.history)
+	return &types.Replay{
+		Board:   g.startingBoard.GetRaw(),
+		Players: g.playerList(),
+		Moves:   moves,
+	}
+}
+
+func (g *Game) sendReplay(conn *sockets.Connection) {
+	g.socketManager.Send(conn, &types.SocketData{Type: sockets.REPLAY, Data: g.replay()})
+}
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestReplayRecordsPlacements(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 1, BlockDegree: 5, Density: 1, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := g.AddPlayer("solo", 0xffffff, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placement := g.players[pid].possiblePlacements.Any().ToSlice()
+	if err := g.PlacePiece(pid, placement); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := g.Replay()
+	if len(replay.Moves) != 1 {
+		t.Fatalf("expected 1 move, got %v", len(replay.Moves))
+	}
+	move := replay.Moves[0]
+	if move.Type != MOVE_PLACE || move.PID != pid || len(move.Placement) != len(placement) {
+		t.Errorf("unexpected move recorded: %+v", move)
+	}
+
+	origin := g.state.board.getOrigin(pid)
+	if !replay.Board[origin.X][origin.Y].IsOrigin() {
+		t.Errorf("starting board is missing the origin")
+	}
+	for _, pt := range placement {
+		if !replay.Board[pt.X][pt.Y].IsVacant() {
+			t.Errorf("starting board should not contain the placement at %+v", pt)
+		}
+	}
+	if len(replay.Players) != 1 {
+		t.Errorf("expected 1 player in the replay, got %v", len(replay.Players))
+	}
+}
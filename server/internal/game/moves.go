@@ -0,0 +1,77 @@
+package game
+
+import (
+	"cmp"
+	"errors"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"slices"
+)
+
+const (
+	defaultMovesLimit = 100
+	maxMovesLimit     = 1000
+)
+
+// A page of the player's legal placements, optionally only those of one piece
+// or those covering an anchor cell. Moves are ordered so pages are stable
+// while the position doesn't change.
+func (g *Game) LegalMoves(pid types.PlayerID, query types.MovesQuery) (*types.LegalMoves, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return nil, err
+	}
+	if (query.X == nil) != (query.Y == nil) {
+		return nil, errors.New("anchor needs both x and y")
+	}
+
+	var candidates []utilities.Set[types.Point]
+	if query.X != nil {
+		candidates = player.possiblePlacements.AtCell(types.Point{X: *query.X, Y: *query.Y})
+	} else if query.Piece != 0 {
+		candidates = player.possiblePlacements.ForPiece(NewPiece(query.Piece))
+	} else {
+		candidates = player.possiblePlacements.All()
+	}
+
+	moves := make([]types.LegalMove, 0, len(candidates))
+	for _, plc := range candidates {
+		hash := PieceFromPoints(plc).Hash()
+		if query.Piece != 0 && hash != NewPiece(query.Piece).Hash() {
+			continue
+		}
+		placement := plc.ToSlice()
+		slices.SortFunc(placement, comparePoints)
+		moves = append(moves, types.LegalMove{Piece: hash, Placement: placement})
+	}
+	slices.SortFunc(moves, func(a, b types.LegalMove) int {
+		if c := cmp.Compare(a.Piece, b.Piece); c != 0 {
+			return c
+		}
+		return slices.CompareFunc(a.Placement, b.Placement, comparePoints)
+	})
+
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = defaultMovesLimit
+	}
+	limit = min(limit, maxMovesLimit)
+	start := min(int(query.Offset), len(moves))
+	end := min(start+limit, len(moves))
+
+	return &types.LegalMoves{
+		Total:  len(moves),
+		Offset: uint(start),
+		Moves:  moves[start:end],
+	}, nil
+}
+
+func comparePoints(a, b types.Point) int {
+	if c := cmp.Compare(a.X, b.X); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Y, b.Y)
+}
@@ -0,0 +1,57 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"slices"
+	"testing"
+)
+
+func TestLegalMoves(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := g.AddPlayer("human", 0xffffff, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := g.state.board.origins[pid]
+
+	all, err := g.LegalMoves(pid, types.MovesQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Total != g.players[pid].possiblePlacements.Size() || len(all.Moves) != all.Total {
+		t.Fatalf("expected all %d placements, got %d of %d", g.players[pid].possiblePlacements.Size(), len(all.Moves), all.Total)
+	}
+
+	single, _ := g.LegalMoves(pid, types.MovesQuery{Piece: monomino.Hash()})
+	if single.Total != 1 || !slices.Equal(single.Moves[0].Placement, types.Placement{origin}) {
+		t.Errorf("expected the monomino on the origin, got %+v", single.Moves)
+	}
+
+	// every first move covers the origin
+	dominoes, _ := g.LegalMoves(pid, types.MovesQuery{Piece: domino.Hash()})
+	anchored, _ := g.LegalMoves(pid, types.MovesQuery{X: &origin.X, Y: &origin.Y, Piece: domino.Hash()})
+	if dominoes.Total == 0 || anchored.Total != dominoes.Total {
+		t.Errorf("expected all %d domino placements over the origin, got %d", dominoes.Total, anchored.Total)
+	}
+	far := origin.X + 50
+	if none, _ := g.LegalMoves(pid, types.MovesQuery{X: &far, Y: &origin.Y}); none.Total != 0 {
+		t.Errorf("expected no placements away from the origin, got %d", none.Total)
+	}
+
+	page, _ := g.LegalMoves(pid, types.MovesQuery{Offset: 2, Limit: 3})
+	if page.Total != all.Total || len(page.Moves) != 3 {
+		t.Fatalf("expected a page of 3, got %d", len(page.Moves))
+	}
+	for ii, move := range page.Moves {
+		if !slices.Equal(move.Placement, all.Moves[ii+2].Placement) {
+			t.Errorf("page doesn't line up with the full list at %d", ii)
+		}
+	}
+
+	if _, err = g.LegalMoves(pid, types.MovesQuery{X: &origin.X}); err == nil {
+		t.Errorf("expected an error for a half anchor")
+	}
+}
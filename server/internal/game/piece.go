@@ -202,25 +202,31 @@ func PieceFromPoints(points utilities.Set[types.Point]) Piece {
 	return NewPiece(v)
 }
 
+// Every square of a piece must be reachable from every other through shared edges
 func ValidPieceCoords(points utilities.Set[pieceCoord]) bool {
-	if points.Size() == 1 {
-		return true
+	if points.Size() == 0 {
+		return false
 	}
+
+	var start pieceCoord
 	for pt := range points {
-		// technically don't need to check the last point, but it's quick
-		hasAdj := false
+		start = pt
+		break
+	}
+	reached := utilities.NewSet([]pieceCoord{start}, points.Size())
+	frontier := []pieceCoord{start}
+	for len(frontier) > 0 {
+		pt := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
 		for _, dir := range []types.Direction{types.UP, types.DOWN, types.LEFT, types.RIGHT} {
 			adj, err := pt.getAdjacent(dir)
-			if err == nil && points.Has(adj) {
-				hasAdj = true
-				break
+			if err == nil && points.Has(adj) && !reached.Has(adj) {
+				reached.Add(adj)
+				frontier = append(frontier, adj)
 			}
 		}
-		if !hasAdj {
-			return false
-		}
 	}
-	return true
+	return reached.Size() == points.Size()
 }
 
 func lsXY(n uint64) (uint8, uint8) {
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"sync"
+)
+
+// One way a piece can lie on the board, as offsets from the piece's corner
+type orientation struct {
+	piece   Piece
+	offsets []types.Point
+}
+
+type generatedSet struct {
+	once   sync.Once
+	pieces PieceSet
+	pixels uint
+	err    error
+}
+
+// Piece sets and orientations are the same for every game, so they're
+// computed once per process and shared
+var pieceCache = struct {
+	mu           sync.RWMutex
+	sets         map[uint8]*generatedSet
+	orientations map[uint64][]orientation
+}{
+	sets:         make(map[uint8]*generatedSet),
+	orientations: make(map[uint64][]orientation),
+}
+
+// Every piece up to the given degree. The caller gets its own copy of the set.
+func cachedPieceSet(degree uint8) (PieceSet, uint, error) {
+	pieceCache.mu.Lock()
+	set, ok := pieceCache.sets[degree]
+	if !ok {
+		set = &generatedSet{}
+		pieceCache.sets[degree] = set
+	}
+	pieceCache.mu.Unlock()
+
+	set.once.Do(func() {
+		set.pieces, set.pixels, set.err = GeneratePieceSet(degree)
+		for piece := range set.pieces {
+			orientationsOf(piece)
+		}
+	})
+	if set.err != nil {
+		return nil, 0, set.err
+	}
+	return set.pieces.Copy(), set.pixels, nil
+}
+
+// The unique orientations of a piece, computed on first use
+func orientationsOf(piece Piece) []orientation {
+	pieceCache.mu.RLock()
+	found, ok := pieceCache.orientations[piece.Hash()]
+	pieceCache.mu.RUnlock()
+	if ok {
+		return found
+	}
+
+	found = computeOrientations(piece)
+
+	pieceCache.mu.Lock()
+	defer pieceCache.mu.Unlock()
+	if cached, ok := pieceCache.orientations[piece.Hash()]; ok {
+		return cached // lost the race, keep the first
+	}
+	pieceCache.orientations[piece.Hash()] = found
+	return found
+}
+
+func computeOrientations(piece Piece) []orientation {
+	found := make([]orientation, 0, 8)
+	attempted := make(map[uint64]bool, 8)
+
+	for j := 0; j < 2; j++ {
+		piece = piece.Reflect(types.X)
+
+		for i := 0; i < 4; i++ {
+			piece = piece.Rotate90()
+			if attempted[piece.repr] {
+				continue // Already tried this one
+			}
+			attempted[piece.repr] = true
+
+			found = append(found, orientation{piece, piece.ToPoints().ToSlice()})
+		}
+	}
+	return found
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOrientations(t *testing.T) {
+	pieces, _, err := cachedPieceSet(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	for piece := range pieces {
+		total += len(orientationsOf(piece))
+	}
+	// the 21 classic pieces can lie on the board 91 different ways
+	if total != 91 {
+		t.Errorf("expected 91 orientations, got %d", total)
+	}
+
+	if n := len(orientationsOf(monomino)); n != 1 {
+		t.Errorf("expected 1 orientation of the monomino, got %d", n)
+	}
+	if n := len(orientationsOf(domino)); n != 2 {
+		t.Errorf("expected 2 orientations of the domino, got %d", n)
+	}
+}
+
+func TestCachedPieceSetIsCopied(t *testing.T) {
+	first, _, _ := cachedPieceSet(3)
+	first.Remove(monomino)
+
+	second, _, _ := cachedPieceSet(3)
+	if !second.Has(monomino) {
+		t.Errorf("changes to one game's pieces leaked into the cache")
+	}
+}
+
+func BenchmarkPieceSet(b *testing.B) {
+	for degree := uint8(6); degree <= MaxPieceDegree; degree++ {
+		b.Run(fmt.Sprintf("generate/degree-%d", degree), func(b *testing.B) {
+			for ii := 0; ii < b.N; ii++ {
+				pieces, _, _ := GeneratePieceSet(degree)
+				for piece := range pieces {
+					computeOrientations(piece)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("cached/degree-%d", degree), func(b *testing.B) {
+			cachedPieceSet(degree)
+			b.ResetTimer()
+			for ii := 0; ii < b.N; ii++ {
+				pieces, _, _ := cachedPieceSet(degree)
+				for piece := range pieces {
+					orientationsOf(piece)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
-package game_test
+package game
 
 import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
 	"testing"
 )
 
 func TestGeneratingNextPieces(t *testing.T) {
-	p := PieceFromPoints(NewSet[PieceCoord]([]PieceCoord{{0, 0}}))
+	p := PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}}))
 	generated := generateNextPieces(p)
 	expectedSize := 1
 	resultSize := generated.Size()
@@ -13,7 +15,7 @@ func TestGeneratingNextPieces(t *testing.T) {
 	if expectedSize != resultSize {
 		t.Errorf("Next piece generation failed. Expected set of size %v, got size %v\n", expectedSize, resultSize)
 	}
-	expected := PieceFromPoints(NewSet[PieceCoord]([]PieceCoord{{0, 0}, {0, 1}}))
+	expected := PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}))
 	for piece := range generated {
 		if !piece.IsSame(expected) {
 			t.Errorf("Next piece generation failed. expected:\n%s\ngot:\n%s\n", expected.ToString(), piece.ToString())
@@ -26,7 +28,7 @@ func TestPieceGenerator(t *testing.T) {
 	expectedSizes := []int{0, 1, 2, 4, 9, 21}
 
 	for ii, degree := range []uint8{0, 1, 2, 3, 4, 5} {
-		result, err := GeneratePieceSet(degree)
+		result, _, err := GeneratePieceSet(degree)
 		if err != nil {
 			t.Errorf("generator returned error: %s", err)
 		}
@@ -0,0 +1,119 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+)
+
+// Named piece sets selectable in the game config
+const (
+	PIECES_CLASSIC     = "classic"     // the 21 pieces of the original game
+	PIECES_PENTOMINOES = "pentominoes" // the 12 pieces of size 5
+)
+
+var piecePresets = map[string]struct{ minDegree, maxDegree uint8 }{
+	PIECES_CLASSIC:     {1, 5},
+	PIECES_PENTOMINOES: {5, 5},
+}
+
+// Work out the pieces each player starts with, and how many squares they cover.
+// An explicit piece list wins over a preset, which wins over the block degree.
+// The degree range then narrows whichever set was chosen.
+func startingPieceSet(config types.GameConfig) (PieceSet, uint, error) {
+	var pieces PieceSet
+	var err error
+
+	if len(config.Pieces) > 0 {
+		pieces, err = piecesFromList(config.Pieces)
+	} else if config.PieceSet != "" {
+		preset, ok := piecePresets[config.PieceSet]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown piece set %q", config.PieceSet)
+		}
+		pieces, _, err = cachedPieceSet(preset.maxDegree)
+		pieces = filterPieces(pieces, preset.minDegree, preset.maxDegree)
+	} else {
+		pieces, _, err = cachedPieceSet(config.BlockDegree)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	maxDegree := MaxPieceDegree
+	if config.BlockDegree > 0 {
+		maxDegree = config.BlockDegree
+	}
+	pieces = filterPieces(pieces, config.MinDegree, maxDegree)
+	if pieces.Size() == 0 {
+		return nil, 0, errors.New("no pieces left to play with")
+	}
+
+	var pix uint
+	for piece := range pieces {
+		pix += uint(piece.Size())
+	}
+	return pieces, pix, nil
+}
+
+func filterPieces(pieces PieceSet, minDegree, maxDegree uint8) PieceSet {
+	filtered := PieceSet{}
+	for piece := range pieces {
+		if size := piece.Size(); size >= minDegree && size <= maxDegree {
+			filtered.Add(piece)
+		}
+	}
+	return filtered
+}
+
+// Build a piece set from pieces given either by hash or by their squares
+func piecesFromList(list []types.PublicPiece) (PieceSet, error) {
+	pieces := PieceSet{}
+	for ii, spec := range list {
+		var piece Piece
+		var err error
+		switch {
+		case spec.Hash != 0 && len(spec.Body) > 0:
+			err = errors.New("give either a hash or a body, not both")
+		case spec.Hash != 0:
+			piece, err = pieceFromHash(spec.Hash)
+		case len(spec.Body) > 0:
+			piece, err = pieceFromBody(spec.Body)
+		default:
+			err = errors.New("empty piece")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("piece %d: %w", ii, err)
+		}
+		pieces.Add(piece)
+	}
+	return pieces, nil
+}
+
+func pieceFromHash(hash uint64) (Piece, error) {
+	piece := NewPiece(normalize64(hash))
+	if !ValidPieceCoords(piece.toCoords()) {
+		return Piece{}, fmt.Errorf("hash %x is not a connected piece", hash)
+	}
+	return piece, nil
+}
+
+func pieceFromBody(body []types.Point) (Piece, error) {
+	points, _ := utilities.NormalizeToOrigin(utilities.NewSet(body))
+	if points.Size() != len(body) {
+		return Piece{}, errors.New("piece has repeated squares")
+	}
+
+	coords := utilities.NewSet([]pieceCoord{}, points.Size())
+	for pt := range points {
+		if pt.X >= int(MaxPieceDegree) || pt.Y >= int(MaxPieceDegree) {
+			return Piece{}, fmt.Errorf("piece does not fit in %dx%d", MaxPieceDegree, MaxPieceDegree)
+		}
+		coords.Add(pieceCoord{uint8(pt.X), uint8(pt.Y)})
+	}
+	if !ValidPieceCoords(coords) {
+		return Piece{}, errors.New("piece squares are not connected")
+	}
+	return PieceFromPoints(points), nil
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestPiecePresets(t *testing.T) {
+	cases := []struct {
+		config types.GameConfig
+		pieces int
+		pixels uint
+	}{
+		{types.GameConfig{PieceSet: PIECES_CLASSIC}, 21, 89},
+		{types.GameConfig{PieceSet: PIECES_PENTOMINOES}, 12, 60},
+		{types.GameConfig{BlockDegree: 5, MinDegree: 3}, 2 + 5 + 12, 6 + 20 + 60},
+		{types.GameConfig{PieceSet: PIECES_CLASSIC, BlockDegree: 2}, 2, 3},
+	}
+	for _, tc := range cases {
+		pieces, pixels, err := startingPieceSet(tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pieces.Size() != tc.pieces || pixels != tc.pixels {
+			t.Errorf("%+v: expected %d pieces covering %d squares, got %d covering %d",
+				tc.config, tc.pieces, tc.pixels, pieces.Size(), pixels)
+		}
+	}
+
+	for _, config := range []types.GameConfig{
+		{},
+		{PieceSet: "tetris"},
+		{BlockDegree: 3, MinDegree: 4},
+	} {
+		if _, _, err := startingPieceSet(config); err == nil {
+			t.Errorf("%+v: expected an error", config)
+		}
+	}
+}
+
+func TestExplicitPieces(t *testing.T) {
+	lTromino := []types.Point{{X: 5, Y: 5}, {X: 5, Y: 6}, {X: 6, Y: 6}}
+	config := types.GameConfig{Pieces: []types.PublicPiece{
+		{Hash: monomino.Hash()},
+		{Body: lTromino},
+		{Body: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}}}, // same piece, rotated
+	}}
+	pieces, pixels, err := startingPieceSet(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pieces.Size() != 2 || pixels != 4 {
+		t.Errorf("expected 2 distinct pieces covering 4 squares, got %d covering %d", pieces.Size(), pixels)
+	}
+
+	invalid := [][]types.PublicPiece{
+		{{}},
+		{{Body: []types.Point{{X: 0, Y: 0}, {X: 2, Y: 0}}}},
+		{{Body: []types.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}}},
+		{{Body: []types.Point{{X: 0, Y: 0}, {X: 0, Y: 0}}}},
+		{{Body: []types.Point{{X: 0, Y: 0}, {X: 9, Y: 0}}}},
+		{{Hash: 0b101}},
+		{{Hash: 1, Body: lTromino}},
+	}
+	for _, list := range invalid {
+		if _, _, err := startingPieceSet(types.GameConfig{Pieces: list}); err == nil {
+			t.Errorf("expected an error for %+v", list)
+		}
+	}
+}
+
+func TestRestoreWithPreset(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, PieceSet: PIECES_PENTOMINOES, Density: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.AddPlayer("human", 0xffffff, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RestoreGame(g.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,25 +2,26 @@ package game_test
 
 import (
 	"gobloks/internal/game"
+	"gobloks/internal/types"
 	"gobloks/internal/utilities"
 	"testing"
 )
 
 func TestPieceCornerFinder(t *testing.T) {
-	var corners, expected []game.PieceCoord
+	var corners, expected []types.Point
 	var p game.Piece
 
-	p = game.PieceFromPoints(utilities.NewSet([]game.PieceCoord{{X: 0, Y: 0}, {X: 0, Y: 1}}))
+	p = game.PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}))
 	corners = p.Corners()
-	expected = []game.PieceCoord{{X: 1, Y: 2}}
+	expected = []types.Point{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: -1, Y: 2}, {X: 1, Y: 2}}
 
 	if len(corners) != len(expected) {
 		t.Errorf("expected %v corners, got %v", len(expected), len(corners))
 	}
 
-	p = game.PieceFromPoints(utilities.NewSet([]game.PieceCoord{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}))
+	p = game.PieceFromPoints(utilities.NewSet([]types.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}))
 	corners = p.Corners()
-	expected = []game.PieceCoord{{X: 0, Y: 2}, {X: 2, Y: 2}}
+	expected = []types.Point{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: -1, Y: 2}, {X: 2, Y: 0}, {X: 2, Y: 2}}
 
 	if len(corners) != len(expected) {
 		t.Errorf("expected %v corners, got %v", len(expected), len(corners))
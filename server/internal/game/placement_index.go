@@ -0,0 +1,137 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+)
+
+// A player's legal placements, indexed by the cells they cover and the piece
+// they use, so a move only touches the placements it affects
+type PlacementIndex struct {
+	placements map[placementKey]utilities.Set[types.Point]
+	byCell     map[types.Point]utilities.Set[placementKey]
+	byPiece    map[uint64]utilities.Set[placementKey]
+}
+
+func NewPlacementIndex(placements ...utilities.Set[types.Point]) *PlacementIndex {
+	index := &PlacementIndex{
+		placements: make(map[placementKey]utilities.Set[types.Point], len(placements)),
+		byCell:     make(map[types.Point]utilities.Set[placementKey]),
+		byPiece:    make(map[uint64]utilities.Set[placementKey]),
+	}
+	for _, points := range placements {
+		index.Add(points)
+	}
+	return index
+}
+
+// Add a placement, returning false if it was already indexed
+func (index *PlacementIndex) Add(points utilities.Set[types.Point]) bool {
+	key := keyOf(points)
+	if _, ok := index.placements[key]; ok {
+		return false
+	}
+	index.placements[key] = points
+
+	for pt := range points {
+		addKey(index.byCell, pt, key)
+	}
+	addKey(index.byPiece, PieceFromPoints(points).Hash(), key)
+	return true
+}
+
+func (index *PlacementIndex) Has(points utilities.Set[types.Point]) bool {
+	// keys only describe pieces that fit in a piece's bounds, so check the cells too
+	found, ok := index.placements[keyOf(points)]
+	return ok && found.Is(points)
+}
+
+func (index *PlacementIndex) Size() int {
+	return len(index.placements)
+}
+
+func (index *PlacementIndex) Empty() bool {
+	return len(index.placements) == 0
+}
+
+// Any one of the placements, or nil if there are none
+func (index *PlacementIndex) Any() utilities.Set[types.Point] {
+	for _, points := range index.placements {
+		return points
+	}
+	return nil
+}
+
+func (index *PlacementIndex) All() []utilities.Set[types.Point] {
+	all := make([]utilities.Set[types.Point], 0, len(index.placements))
+	for _, points := range index.placements {
+		all = append(all, points)
+	}
+	return all
+}
+
+// Placements of one piece, in any orientation
+func (index *PlacementIndex) ForPiece(piece Piece) []utilities.Set[types.Point] {
+	keys := index.byPiece[piece.Hash()]
+	found := make([]utilities.Set[types.Point], 0, keys.Size())
+	for key := range keys {
+		found = append(found, index.placements[key])
+	}
+	return found
+}
+
+// Placements covering a cell
+func (index *PlacementIndex) AtCell(pt types.Point) []utilities.Set[types.Point] {
+	keys := index.byCell[pt]
+	found := make([]utilities.Set[types.Point], 0, keys.Size())
+	for key := range keys {
+		found = append(found, index.placements[key])
+	}
+	return found
+}
+
+// Drop every placement that covers any of the cells
+func (index *PlacementIndex) RemoveCovering(cells ...types.Point) {
+	for _, pt := range cells {
+		for key := range index.byCell[pt] {
+			index.remove(key)
+		}
+	}
+}
+
+// Drop every placement of a piece, once it has been played
+func (index *PlacementIndex) RemovePiece(piece Piece) {
+	for key := range index.byPiece[piece.Hash()] {
+		index.remove(key)
+	}
+}
+
+func (index *PlacementIndex) remove(key placementKey) {
+	points, ok := index.placements[key]
+	if !ok {
+		return
+	}
+	delete(index.placements, key)
+	for pt := range points {
+		removeKey(index.byCell, pt, key)
+	}
+	removeKey(index.byPiece, PieceFromPoints(points).Hash(), key)
+}
+
+func addKey[K comparable](keys map[K]utilities.Set[placementKey], at K, key placementKey) {
+	set, ok := keys[at]
+	if !ok {
+		set = utilities.NewSet([]placementKey{})
+		keys[at] = set
+	}
+	set.Add(key)
+}
+
+func removeKey[K comparable](keys map[K]utilities.Set[placementKey], at K, key placementKey) {
+	if set, ok := keys[at]; ok {
+		set.Remove(key)
+		if set.Size() == 0 {
+			delete(keys, at)
+		}
+	}
+}
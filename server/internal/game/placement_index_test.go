@@ -0,0 +1,71 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"testing"
+)
+
+func TestPlacementIndex(t *testing.T) {
+	vertical := utilities.NewSet([]types.Point{{X: 1, Y: 1}, {X: 1, Y: 2}})
+	horizontal := utilities.NewSet([]types.Point{{X: 1, Y: 1}, {X: 2, Y: 1}})
+	single := utilities.NewSet([]types.Point{{X: 3, Y: 3}})
+
+	index := NewPlacementIndex(vertical, horizontal, single)
+	if index.Add(utilities.NewSet([]types.Point{{X: 1, Y: 2}, {X: 1, Y: 1}})) {
+		t.Errorf("the same placement should only be indexed once")
+	}
+	if index.Size() != 3 || len(index.AtCell(types.Point{X: 1, Y: 1})) != 2 || len(index.ForPiece(domino)) != 2 {
+		t.Fatalf("unexpected index contents %v", index.All())
+	}
+
+	// cells outside a piece's bounds must not alias a real placement
+	if index.Has(utilities.NewSet([]types.Point{{X: 1, Y: 1}, {X: 9, Y: 1}})) {
+		t.Errorf("malformed placement matched an indexed one")
+	}
+
+	index.RemovePiece(domino)
+	if index.Size() != 1 || !index.Has(single) || len(index.AtCell(types.Point{X: 1, Y: 1})) != 0 {
+		t.Errorf("expected only the monomino to remain, got %v", index.All())
+	}
+
+	index.RemoveCovering(types.Point{X: 3, Y: 3})
+	if !index.Empty() || index.Any() != nil {
+		t.Errorf("expected an empty index, got %v", index.All())
+	}
+}
+
+func TestPlacementIndexStaysCurrent(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 3, BlockDegree: 4, Density: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ii := 0; ii < 3; ii++ {
+		if _, err = g.AddPlayer("player", 0xffffff, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for round := 0; round < 3; round++ {
+		for pid, player := range g.players {
+			if player.possiblePlacements.Empty() {
+				continue
+			}
+			if err := g.PlacePiece(pid, player.possiblePlacements.Any().ToSlice()); err != nil {
+				t.Fatal(err)
+			}
+
+			for other, p := range g.players {
+				expected := g.state.board.findPlacements(other, p.state.pieces)
+				if len(expected) != p.possiblePlacements.Size() {
+					t.Fatalf("player %d: expected %d placements, index has %d", other, len(expected), p.possiblePlacements.Size())
+				}
+				for _, plc := range expected {
+					if !p.possiblePlacements.Has(plc) {
+						t.Fatalf("player %d: index is missing %v", other, plc.ToSlice())
+					}
+				}
+			}
+		}
+	}
+}
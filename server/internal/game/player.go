@@ -14,6 +14,8 @@ const (
 	TIMED_OUT types.Flags = (1 << 3) // has timed out
 	WINNER    types.Flags = (1 << 4) // has won
 	DRAWN     types.Flags = (1 << 5) // has drawn
+	BOT       types.Flags = (1 << 6) // played by the server
+	RESIGNED  types.Flags = (1 << 7) // has resigned
 )
 
 const PID_NONE types.PlayerID = 0
@@ -25,8 +27,12 @@ type Player struct {
 	socket             *sockets.Connection
 	playerTimer        *utilities.Timer
 	connectionTimer    *utilities.Timer
-	possiblePlacements utilities.LinkedList[utilities.Set[types.Point]]
+	possiblePlacements *PlacementIndex
 	hints              uint
+	hintLog            *hintLog
+	bot                *Bot
+	reclaimHash        []byte // sha256 of the secret that gives the seat back
+	tokenGeneration    uint   // tokens from earlier generations are revoked
 }
 
 type PlayerState struct {
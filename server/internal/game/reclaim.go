@@ -0,0 +1,93 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"gobloks/internal/types"
+	"time"
+)
+
+const reclaimSecretBytes = 24
+
+var errBadReclaim = errors.New("invalid reclaim secret")
+
+// A new secret that lets whoever holds it take back the seat, replacing any earlier one
+func (g *Game) IssueReclaimSecret(pid types.PlayerID) (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return "", err
+	}
+
+	secret, hash, err := newReclaimSecret()
+	if err != nil {
+		return "", err
+	}
+	player.reclaimHash = hash
+	g.saveSnapshot()
+	return secret, nil
+}
+
+// Exchange a reclaim secret for a new one, revoking the seat's tokens. issue
+// makes the token for the new device, and nothing changes unless it succeeds.
+// The old device is disconnected, and the seat gets a fresh grace period to
+// connect from wherever the player is now.
+func (g *Game) Reclaim(pid types.PlayerID, secret string, issue func(generation uint) error) (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil || player.reclaimHash == nil {
+		return "", errBadReclaim
+	}
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(sum[:], player.reclaimHash) != 1 {
+		return "", errBadReclaim
+	}
+	if player.state.status.Has(DISABLED) {
+		return "", errors.New("seat has already been given up")
+	}
+
+	next, hash, err := newReclaimSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := issue(player.tokenGeneration + 1); err != nil {
+		return "", err
+	}
+	player.reclaimHash = hash
+	player.revokeTokens()
+
+	if player.socket != nil {
+		g.socketManager.Close(player.socket)
+		player.socket = nil
+		player.state.status.Clear(CONNECTED)
+		g.sendPlayerList()
+		g.sendGameStatus()
+	}
+	if !g.state.status.Has(COMPLETE) {
+		if player.connectionTimer != nil {
+			player.connectionTimer.Pause()
+		}
+		g.startConnectionTimer(player)
+	}
+	g.lastActive = time.Now()
+	g.saveSnapshot()
+	return next, nil
+}
+
+// A random secret and the hash kept of it
+func newReclaimSecret() (string, []byte, error) {
+	b := make([]byte, reclaimSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	sum := sha256.Sum256([]byte(secret))
+	return secret, sum[:], nil
+}
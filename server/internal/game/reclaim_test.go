@@ -0,0 +1,156 @@
+package game
+
+import (
+	"errors"
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialPlayer(t *testing.T, g *Game, pid types.PlayerID) *websocket.Conn {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err = g.ConnectSocket(conn, pid); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// Stands in for making the new device's token
+func issueNothing(uint) error { return nil }
+
+func TestReclaimSecret(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+	secret, err := g.IssueReclaimSecret(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Reclaim(pid, "guess", issueNothing); err == nil {
+		t.Error("wrong secret accepted")
+	}
+	if _, err := g.Reclaim(pid+1, secret, issueNothing); err == nil {
+		t.Error("secret accepted for an empty seat")
+	}
+
+	// the secret is kept if the new token can't be made
+	if _, err := g.Reclaim(pid, secret, func(uint) error { return errors.New("no key") }); err == nil {
+		t.Error("expected the issue error back")
+	}
+
+	next, err := g.Reclaim(pid, secret, issueNothing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Reclaim(pid, secret, issueNothing); err == nil {
+		t.Error("used secret accepted again")
+	}
+
+	// the current secret survives a restore
+	restored, err := RestoreGame(g.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.Reclaim(pid, next, issueNothing); err != nil {
+		t.Errorf("secret lost in restore: %v", err)
+	}
+}
+
+func TestReclaimGivenUpSeat(t *testing.T) {
+	g, _ := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+	secret, _ := g.IssueReclaimSecret(pid)
+
+	g.players[pid].state.status.Set(DISABLED)
+	if _, err := g.Reclaim(pid, secret, issueNothing); err == nil {
+		t.Error("reclaimed a disabled seat")
+	}
+}
+
+func TestReclaimMovesConnection(t *testing.T) {
+	g, _ := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+
+	old := dialPlayer(t, g, pid)
+	readUntil(t, old, sockets.BOARD_STATE)
+
+	replacement := dialPlayer(t, g, pid)
+	readUntil(t, replacement, sockets.BOARD_STATE)
+
+	// the old connection is closed, without disconnecting the seat
+	old.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg types.SocketData
+		if err := old.ReadJSON(&msg); err != nil {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	player := g.players[pid]
+	if !player.state.status.Has(CONNECTED) || player.socket == nil {
+		t.Error("seat disconnected when the old connection closed")
+	}
+	if player.connectionTimer != nil {
+		t.Error("disconnect timer started for a live seat")
+	}
+}
+
+func TestReclaimDisconnectsOldDevice(t *testing.T) {
+	g, _ := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+	secret, _ := g.IssueReclaimSecret(pid)
+
+	old := dialPlayer(t, g, pid)
+	readUntil(t, old, sockets.BOARD_STATE)
+
+	if _, err := g.Reclaim(pid, secret, issueNothing); err != nil {
+		t.Fatal(err)
+	}
+
+	old.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg types.SocketData
+		if err := old.ReadJSON(&msg); err != nil {
+			if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+				t.Fatal("old device still connected after the seat was reclaimed")
+			}
+			break
+		}
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	player := g.players[pid]
+	if player.state.status.Has(CONNECTED) || player.socket != nil {
+		t.Error("seat still attached to the old device")
+	}
+	if player.connectionTimer == nil {
+		t.Error("no grace period for the new device to connect")
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+)
+
+// Scoring modes
+const (
+	SCORING_REMAINING types.ScoringMode = iota // fewest squares left wins
+	SCORING_OFFICIAL                           // standard Blokus scoring with bonuses
+)
+
+const (
+	BONUS_ALL_PLACED    = 15 // placed every piece
+	BONUS_MONOMINO_LAST = 5  // ...and the monomino was the last one down
+)
+
+// Whether a player can be among the winners. Resigned players keep their
+// score, but gave up any claim to the win with it.
+func (player *Player) contending() bool {
+	return !player.state.status.Has(RESIGNED)
+}
+
+// Final score of every seated player, higher is better
+func (g *Game) scores() map[types.PlayerID]int {
+	scores := make(map[types.PlayerID]int, len(g.players))
+	for pid, player := range g.players {
+		if player == nil {
+			continue
+		}
+		score := 0
+		for piece := range player.state.pieces {
+			score -= int(piece.Size())
+		}
+		if g.config.Scoring == SCORING_OFFICIAL && player.state.pieces.Size() == 0 {
+			score += BONUS_ALL_PLACED
+			if last := g.lastPlacement(pid); len(last) == 1 {
+				score += BONUS_MONOMINO_LAST
+			}
+		}
+		scores[pid] = score
+	}
+	return scores
+}
+
+// The most recent placement by a player that still stands on the board
+func (g *Game) lastPlacement(pid types.PlayerID) types.Placement {
+	placed := make([]types.Placement, 0)
+	for _, move := range g.history {
+		if move.PID != pid {
+			continue
+		}
+		switch move.Type {
+		case MOVE_PLACE:
+			placed = append(placed, move.Placement)
+		case MOVE_TAKEBACK:
+			if len(placed) > 0 && utilities.NewSet(placed[len(placed)-1]).Is(utilities.NewSet(move.Placement)) {
+				placed = placed[:len(placed)-1]
+			}
+		}
+	}
+	if len(placed) == 0 {
+		return nil
+	}
+	return placed[len(placed)-1]
+}
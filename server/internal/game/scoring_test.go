@@ -0,0 +1,61 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestScoringModes(t *testing.T) {
+	g, err := InitGame("SCORE", types.GameConfig{Players: 3, BlockDegree: 2, Density: 0.5, Scoring: SCORING_OFFICIAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+	p3, _ := g.AddPlayer("three", 0x0000ff, 0)
+
+	mono := types.Placement{{X: 0, Y: 0}}
+	domino := types.Placement{{X: 5, Y: 5}, {X: 5, Y: 6}}
+
+	// player 1 finished with the monomino, player 2 with the domino
+	g.players[p1].state.pieces = PieceSet{}
+	g.players[p2].state.pieces = PieceSet{}
+	g.history = []types.Move{
+		{Type: MOVE_PLACE, PID: p1, Placement: domino},
+		{Type: MOVE_PLACE, PID: p2, Placement: mono},
+		{Type: MOVE_PLACE, PID: p1, Placement: mono},
+		{Type: MOVE_PLACE, PID: p2, Placement: domino},
+	}
+	// player 3 still holds both pieces
+
+	scores := g.scores()
+	expected := map[types.PlayerID]int{p1: 20, p2: 15, p3: -3}
+	for pid, score := range expected {
+		if scores[pid] != score {
+			t.Errorf("official scoring: player %v expected %v, got %v", pid, score, scores[pid])
+		}
+	}
+
+	winners := g.determineWinners()
+	if len(winners) != 1 || winners[0] != g.players[p1] {
+		t.Errorf("expected player %v to win", p1)
+	}
+
+	// a monomino taken back no longer counts as the last piece
+	g.history = append(g.history, types.Move{Type: MOVE_TAKEBACK, PID: p1, Placement: mono})
+	if score := g.scores()[p1]; score != 15 {
+		t.Errorf("expected %v after takeback, got %v", 15, score)
+	}
+
+	g.config.Scoring = SCORING_REMAINING
+	scores = g.scores()
+	expected = map[types.PlayerID]int{p1: 0, p2: 0, p3: -3}
+	for pid, score := range expected {
+		if scores[pid] != score {
+			t.Errorf("remaining scoring: player %v expected %v, got %v", pid, score, scores[pid])
+		}
+	}
+	if winners := g.determineWinners(); len(winners) != 2 {
+		t.Errorf("expected a draw between 2 players, got %v winners", len(winners))
+	}
+}
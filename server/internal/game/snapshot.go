@@ -0,0 +1,329 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+	"sync"
+	"time"
+)
+
+// Serializable state of a game, sufficient to rebuild it after a restart
+type Snapshot struct {
+	GID          types.GameID                   `json:"gid"`
+	Config       types.GameConfig               `json:"config"`
+	Board        [][]types.Owner                `json:"board"`
+	Start        [][]types.Owner                `json:"start"`
+	Origins      map[types.PlayerID]types.Point `json:"origins"`
+	Turn         types.PlayerID                 `json:"turn"`
+	Status       types.Flags                    `json:"status"`
+	Players      []PlayerSnapshot               `json:"players"`
+	History      []types.Move                   `json:"history"`
+	CreatedAt    time.Time                      `json:"createdAt"`
+	LastActive   time.Time                      `json:"lastActive"`
+	PasswordHash []byte                         `json:"passwordHash,omitempty"`
+	Analysis     *types.Analysis                `json:"analysis,omitempty"`
+}
+
+type PlayerSnapshot struct {
+	PID        types.PlayerID `json:"pid"`
+	Name       string         `json:"name"`
+	Color      uint           `json:"color"`
+	Status     types.Flags    `json:"status"`
+	Pieces     []uint64       `json:"pieces"`
+	Hints      uint           `json:"hints"`
+	Time       uint           `json:"timeMs"`
+	Bot        types.BotLevel `json:"bot,omitempty"`
+	Reclaim    []byte         `json:"reclaim,omitempty"`
+	Generation uint           `json:"generation,omitempty"`
+	HintsGiven *HintSnapshot  `json:"hintsGiven,omitempty"`
+}
+
+// Hints already given to a player, so a restart doesn't offer them again
+type HintSnapshot struct {
+	Corners    []types.Point     `json:"corners,omitempty"`
+	Pieces     []uint64          `json:"pieces,omitempty"`
+	Placements []types.Placement `json:"placements,omitempty"`
+}
+
+// Register a callback to receive a snapshot whenever the game changes. It is
+// called in the background, and only with the latest snapshot if the game has
+// moved on while the previous one was being saved.
+func (g *Game) OnSnapshot(persist func(*Snapshot)) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.writer != nil {
+		g.writer.stop()
+	}
+	g.writer = &snapshotWriter{persist: persist}
+	g.writer.idle = sync.NewCond(&g.writer.lock)
+}
+
+// Wait until every snapshot handed out so far has been saved
+func (g *Game) Flush() {
+	g.lock.Lock()
+	writer := g.writer
+	g.lock.Unlock()
+	if writer != nil {
+		writer.flush()
+	}
+}
+
+func (g *Game) Snapshot() *Snapshot {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.snapshot()
+}
+
+// Queue the current state for the persistence callback, if there is one.
+// Must be called with the game lock held.
+func (g *Game) saveSnapshot() {
+	if g.writer != nil {
+		g.writer.queue(g.snapshot())
+	}
+}
+
+// Saves a game's snapshots one at a time off the game lock, so slow storage
+// doesn't hold up play. Snapshots queued during a save replace each other.
+type snapshotWriter struct {
+	lock    sync.Mutex
+	idle    *sync.Cond // signalled when the last queued snapshot is saved
+	persist func(*Snapshot)
+	pending *Snapshot
+	running bool
+	stopped bool
+}
+
+func (w *snapshotWriter) queue(snap *Snapshot) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return
+	}
+	w.pending = snap
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+}
+
+func (w *snapshotWriter) run() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for w.pending != nil {
+		snap := w.pending
+		w.pending = nil
+		w.lock.Unlock()
+		w.persist(snap)
+		w.lock.Lock()
+	}
+	w.running = false
+	w.idle.Broadcast()
+}
+
+func (w *snapshotWriter) flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for w.running {
+		w.idle.Wait()
+	}
+}
+
+// Drop anything not yet saved and wait out a save under way, after which
+// nothing more is persisted
+func (w *snapshotWriter) stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stopped = true
+	w.pending = nil
+	for w.running {
+		w.idle.Wait()
+	}
+}
+
+func (g *Game) snapshot() *Snapshot {
+	board := g.state.board.Copy()
+	start := g.startingBoard.Copy()
+
+	players := make([]PlayerSnapshot, 0, len(g.players))
+	for pid, player := range g.players {
+		if player == nil {
+			continue
+		}
+		pieces := make([]uint64, 0, player.state.pieces.Size())
+		for piece := range player.state.pieces {
+			pieces = append(pieces, piece.Hash())
+		}
+		ps := PlayerSnapshot{
+			PID:        pid,
+			Name:       player.name,
+			Color:      player.color,
+			Status:     player.state.status,
+			Pieces:     pieces,
+			Hints:      player.hints,
+			Time:       player.playerTimer.TimeLeftMs(),
+			Reclaim:    player.reclaimHash,
+			Generation: player.tokenGeneration,
+		}
+		if player.bot != nil {
+			ps.Bot = player.bot.level
+		}
+		if player.hintLog != nil {
+			ps.HintsGiven = player.hintLog.snapshot()
+		}
+		players = append(players, ps)
+	}
+
+	history := make([]types.Move, len(g.history))
+	copy(history, g.history)
+
+	return &Snapshot{
+		GID:          g.gid,
+		Config:       g.config,
+		Board:        board.layout,
+		Start:        start.layout,
+		Origins:      board.origins,
+		Turn:         g.state.turn,
+		Status:       g.state.status,
+		Players:      players,
+		History:      history,
+		CreatedAt:    g.createdAt,
+		LastActive:   g.lastActive,
+		PasswordHash: g.passwordHash,
+		Analysis:     g.analysis,
+	}
+}
+
+// Rebuild a game from a snapshot. Human players are restored disconnected,
+// and are disabled as usual if they don't reconnect in time.
+func RestoreGame(snap *Snapshot) (*Game, error) {
+	config := snap.Config
+
+	pieces, _, err := startingPieceSet(config)
+	if err != nil {
+		return nil, err
+	}
+
+	board, err := boardFromLayout(snap.Board, snap.Origins)
+	if err != nil {
+		return nil, err
+	}
+	start, err := boardFromLayout(snap.Start, snap.Origins)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make(map[types.PlayerID]*Player, config.Players)
+	for ii := 1; ii <= int(config.Players); ii++ {
+		players[types.PlayerID(ii)] = nil
+	}
+
+	g := &Game{
+		gid:            snap.GID,
+		lock:           &sync.Mutex{},
+		config:         config,
+		startingPieces: pieces,
+		socketManager:  sockets.InitSocketManager(int(config.Players)),
+		createdAt:      snap.CreatedAt,
+		lastActive:     snap.LastActive,
+		evalEngine:     startEvalEngine(),
+		state: &GameState{
+			board,
+			snap.Turn,
+			snap.Status,
+		},
+		players:       players,
+		startingBoard: start,
+		history:       snap.History,
+		passwordHash:  snap.PasswordHash,
+		analysis:      snap.Analysis,
+	}
+	if g.history == nil {
+		g.history = make([]types.Move, 0)
+	}
+	if g.createdAt.IsZero() {
+		g.createdAt = g.lastActive // saved before creation times were kept
+	}
+
+	for _, ps := range snap.Players {
+		if _, ok := players[ps.PID]; !ok {
+			return nil, fmt.Errorf("invalid player id %d", ps.PID)
+		}
+
+		remaining := PieceSet{}
+		for _, hash := range ps.Pieces {
+			piece := NewPiece(hash)
+			if !pieces.Has(piece) {
+				return nil, fmt.Errorf("player %d has unknown piece %x", ps.PID, hash)
+			}
+			remaining.Add(piece)
+		}
+
+		player := &Player{
+			name:  ps.Name,
+			color: ps.Color,
+			state: &PlayerState{
+				pid:    ps.PID,
+				status: ps.Status,
+				pieces: remaining,
+			},
+			playerTimer: utilities.InitTimer(
+				ps.Time,
+				config.TimeBonus*1000,
+				g.handleTimeout,
+				ps.PID,
+			),
+			possiblePlacements: NewPlacementIndex(board.findPlacements(ps.PID, remaining)...),
+			hints:              ps.Hints,
+			reclaimHash:        ps.Reclaim,
+			tokenGeneration:    ps.Generation,
+		}
+		if ps.HintsGiven != nil {
+			player.hintLog = restoreHintLog(ps.HintsGiven)
+		}
+		if ps.Bot != 0 {
+			player.bot = &Bot{level: ps.Bot}
+		} else {
+			player.state.status.Clear(CONNECTED)
+		}
+		players[ps.PID] = player
+	}
+
+	if g.state.status.Has(COMPLETE) {
+		// analysed on first request if it wasn't saved, rather than all at once here
+		g.evalEngine.Stop()
+		return g, nil
+	}
+
+	for _, player := range players {
+		if player != nil && player.bot == nil && !player.state.status.Has(DISABLED) {
+			g.startConnectionTimer(player)
+		}
+	}
+	if g.config.TimeControl > 0 && g.state.status.Has(IN_PROGRESS) {
+		if player := players[g.state.turn]; player != nil {
+			player.playerTimer.Start()
+		}
+	}
+	g.scheduleBots()
+
+	return g, nil
+}
+
+func boardFromLayout(layout [][]types.Owner, origins map[types.PlayerID]types.Point) (*Board, error) {
+	if len(layout) == 0 {
+		return nil, errors.New("empty board layout")
+	}
+	board := &Board{
+		layout:  layout,
+		origins: make(map[types.PlayerID]types.Point, len(origins)),
+		maxX:    uint(len(layout)),
+		maxY:    uint(len(layout[0])),
+	}
+	for pid, pt := range origins {
+		board.origins[pid] = pt
+	}
+	return board, nil
+}
@@ -0,0 +1,134 @@
+package game
+
+import (
+	"encoding/json"
+	"gobloks/internal/types"
+	"testing"
+	"time"
+)
+
+func countPlacements(p *Player) int {
+	return p.possiblePlacements.Size()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	g, err := InitGame("SNAP", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, TurnBased: true, Hints: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+
+	if err := g.PlacePiece(p1, g.players[p1].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(g.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := RestoreGame(&snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.ID() != "SNAP" || restored.state.turn != p2 || !restored.state.status.Has(FULL|IN_PROGRESS) {
+		t.Errorf("game state not restored: %+v", restored.state)
+	}
+	if restored.state.board.ToString() != g.state.board.ToString() {
+		t.Errorf("board not restored:\n%s\nexpected:\n%s", restored.state.board.ToString(), g.state.board.ToString())
+	}
+	if restored.startingBoard.ToString() != g.startingBoard.ToString() {
+		t.Errorf("starting board not restored")
+	}
+	if len(restored.history) != len(g.history) {
+		t.Errorf("expected %v moves, got %v", len(g.history), len(restored.history))
+	}
+
+	for _, pid := range []types.PlayerID{p1, p2} {
+		orig, rest := g.players[pid], restored.players[pid]
+		if rest.name != orig.name || rest.hints != orig.hints {
+			t.Errorf("player %v not restored", pid)
+		}
+		if rest.state.pieces.Size() != orig.state.pieces.Size() {
+			t.Errorf("player %v expected %v pieces, got %v", pid, orig.state.pieces.Size(), rest.state.pieces.Size())
+		}
+		if rest.state.status.Has(CONNECTED) {
+			t.Errorf("player %v should be restored disconnected", pid)
+		}
+		if countPlacements(rest) != len(g.state.board.findPlacements(pid, orig.state.pieces)) {
+			t.Errorf("player %v placements not rebuilt", pid)
+		}
+	}
+
+	// a restored game keeps playing
+	if err := restored.PlacePiece(p2, restored.players[p2].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClosedGameStopsSaving(t *testing.T) {
+	g, _ := InitGame("SNAP", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Bots: []types.BotLevel{BOT_RANDOM}})
+	saved := 0
+	g.OnSnapshot(func(*Snapshot) { saved++ })
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+	g.Flush()
+	if saved == 0 {
+		t.Fatal("expected joining to save the game")
+	}
+
+	g.Close()
+	g.Close() // closing twice is harmless
+	saved = 0
+	if _, err := g.IssueReclaimSecret(pid); err != nil {
+		t.Fatal(err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if saved != 0 {
+		t.Error("closed game saved itself")
+	}
+	if timer := g.players[pid].connectionTimer; timer != nil && timer.TimeLeftMs() == 0 {
+		t.Error("connection timer ran out on a closed game")
+	}
+}
+
+func TestSnapshotsSavedInBackground(t *testing.T) {
+	g, _ := InitGame("SNAP", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	release := make(chan struct{})
+	var saved []*Snapshot
+	g.OnSnapshot(func(snap *Snapshot) {
+		<-release
+		saved = append(saved, snap)
+	})
+
+	// a slow save doesn't hold up the game, and the snapshots queued behind it collapse into one
+	done := make(chan struct{})
+	go func() {
+		g.AddPlayer("one", 0xffffff, 0)
+		g.AddPlayer("two", 0xffffff, 0)
+		g.AddPlayer("three", 0xffffff, 0) // full, so not saved
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("game blocked on a slow save")
+	}
+	close(release)
+	g.Flush()
+
+	// the first may be replaced too, if it was queued before the save began
+	if len(saved) == 0 || len(saved) > 2 {
+		t.Fatalf("expected at most the first snapshot and the latest, got %d", len(saved))
+	}
+	if last := saved[len(saved)-1]; len(last.Players) != 2 {
+		t.Errorf("expected the latest snapshot saved last, got %d players", len(last.Players))
+	}
+}
@@ -0,0 +1,77 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+
+	"github.com/gorilla/websocket"
+)
+
+// Most spectators one game will hold at once
+const MaxSpectators = 32
+
+// Attach a watch-only connection. Spectators receive every public broadcast,
+// but have no seat and so can't act in the game.
+func (g *Game) ConnectSpectator(socket *websocket.Conn) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if socket == nil {
+		return errors.New("no socket")
+	}
+	if g.closed {
+		return errors.New("game has been closed")
+	}
+	if g.spectators >= MaxSpectators {
+		return errors.New("too many spectators")
+	}
+
+	g.spectators++
+	conn := g.socketManager.Connect(socket)
+	go g.receiveSpectatorMessages(conn)
+
+	fmt.Println("Connected spectator to", g.gid)
+
+	g.socketManager.Send(conn, &types.SocketData{Type: sockets.PLAYER_UPDATE, Data: g.playerList()})
+	g.socketManager.Send(conn, &types.SocketData{Type: sockets.GAME_STATUS, Data: g.publicState()})
+	g.socketManager.Send(conn, &types.SocketData{Type: sockets.BOARD_STATE, Data: g.state.board.Copy().GetRaw()})
+
+	return nil
+}
+
+func (g *Game) receiveSpectatorMessages(conn *sockets.Connection) {
+	for {
+		var inMsg types.SocketData
+		err := g.socketManager.Recv(conn, &inMsg)
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		switch inMsg.Type {
+		case sockets.CHAT_MESSAGE:
+			if !g.config.SpectatorChat {
+				continue
+			}
+			var chat types.ChatMessage
+			if err := sockets.DecodeData(&inMsg, &chat); err != nil {
+				continue
+			}
+			// spectators can't speak for a player
+			chat.Origin = types.SPECTATOR
+			g.socketManager.Broadcast(&types.SocketData{Type: sockets.CHAT_MESSAGE, Data: &chat})
+		case sockets.REPLAY:
+			// no password check, the spectator token was only issued after one
+			g.lock.Lock()
+			g.sendReplay(conn)
+			g.lock.Unlock()
+		}
+	}
+
+	g.socketManager.Disconnect(conn)
+	g.lock.Lock()
+	g.spectators--
+	g.lock.Unlock()
+	fmt.Println("Disconnected spectator from", g.gid)
+}
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialSpectator(t *testing.T, g *Game) *websocket.Conn {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err = g.ConnectSpectator(conn); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func readUntil(t *testing.T, ws *websocket.Conn, want types.SocketDataType) map[string]any {
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg struct {
+			Type types.SocketDataType `json:"type"`
+			Data any                  `json:"data"`
+		}
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("never received message type %d: %s", want, err)
+		}
+		if msg.Type == want {
+			data, _ := msg.Data.(map[string]any)
+			return data
+		}
+	}
+}
+
+func TestSpectatorReceivesState(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := dialSpectator(t, g)
+
+	readUntil(t, ws, sockets.BOARD_STATE)
+
+	// broadcasts reach spectators too
+	g.lock.Lock()
+	g.sendGameStatus()
+	g.lock.Unlock()
+	readUntil(t, ws, sockets.GAME_STATUS)
+}
+
+func TestSpectatorChat(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, SpectatorChat: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := dialSpectator(t, g)
+	readUntil(t, ws, sockets.BOARD_STATE)
+
+	err = ws.WriteJSON(&types.SocketData{
+		Type: sockets.CHAT_MESSAGE,
+		Data: &types.ChatMessage{Origin: types.Owner(1), Message: "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat := readUntil(t, ws, sockets.CHAT_MESSAGE)
+	if types.Owner(chat["origin"].(float64)) != types.SPECTATOR {
+		t.Errorf("spectator chat should not be attributed to a player, got origin %v", chat["origin"])
+	}
+}
+
+func TestPlayerChat(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+	ws := dialPlayer(t, g, pid)
+	readUntil(t, ws, sockets.BOARD_STATE)
+
+	err = ws.WriteJSON(&types.SocketData{
+		Type: sockets.CHAT_MESSAGE,
+		Data: &types.ChatMessage{Origin: types.Owner(pid + 1), Message: "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// skip over the game's own announcements
+	chat := readUntil(t, ws, sockets.CHAT_MESSAGE)
+	for chat["message"] != "hello" {
+		chat = readUntil(t, ws, sockets.CHAT_MESSAGE)
+	}
+	if types.Owner(chat["origin"].(float64)) != types.Owner(pid) {
+		t.Errorf("chat should be attributed to its sender %v, got origin %v", pid, chat["origin"])
+	}
+}
+
+func TestSpectatorLimit(t *testing.T) {
+	g, err := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.lock.Lock()
+	g.spectators = MaxSpectators - 1
+	g.lock.Unlock()
+
+	last := dialSpectator(t, g)
+	readUntil(t, last, sockets.BOARD_STATE)
+
+	refused := make(chan error, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		refused <- g.ConnectSpectator(conn)
+	}))
+	defer server.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if err := <-refused; err == nil {
+		t.Error("spectator let in past the limit")
+	}
+
+	// leaving frees the place up again
+	last.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		g.lock.Lock()
+		spectators := g.spectators
+		g.lock.Unlock()
+		if spectators == MaxSpectators-1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("spectator count stuck at %d after one left", spectators)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
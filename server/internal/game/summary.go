@@ -0,0 +1,34 @@
+package game
+
+import "gobloks/internal/types"
+
+// What the lobby shows about a game
+func (g *Game) Summary() types.GameSummary {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var taken uint
+	for _, player := range g.players {
+		if player != nil {
+			taken++
+		}
+	}
+
+	return types.GameSummary{
+		GID:         g.gid,
+		Players:     g.config.Players,
+		Degree:      g.config.BlockDegree,
+		PieceSet:    g.config.PieceSet,
+		Shape:       g.config.Shape,
+		TurnBased:   g.config.TurnBased,
+		TimeControl: g.config.TimeControl,
+		TimeBonus:   g.config.TimeBonus,
+		Teams:       g.config.Teams,
+		Visibility:  g.config.Visibility,
+		SeatsTaken:  taken,
+		SeatsOpen:   g.config.Players - taken,
+		Status:      g.state.status,
+		CreatedAt:   g.createdAt,
+		LastActive:  g.lastActive,
+	}
+}
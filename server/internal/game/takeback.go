@@ -0,0 +1,169 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/sockets"
+	"gobloks/internal/types"
+	"gobloks/internal/utilities"
+)
+
+// Everything needed to reverse the most recent placement
+type undoState struct {
+	seq       uint
+	pid       types.PlayerID
+	placement utilities.Set[types.Point]
+	piece     Piece
+	turn      types.PlayerID
+	statuses  map[types.PlayerID]types.Flags
+	clocks    map[types.PlayerID]uint
+}
+
+type takebackRequest struct {
+	seq       uint
+	pid       types.PlayerID
+	responses map[types.PlayerID]bool
+}
+
+// Capture the game before a placement is committed. Must be called with the game lock held.
+func (g *Game) captureUndo(player *Player, placement utilities.Set[types.Point]) *undoState {
+	undo := &undoState{
+		seq:       uint(len(g.history)),
+		pid:       player.state.pid,
+		placement: placement,
+		piece:     PieceFromPoints(placement),
+		turn:      g.state.turn,
+		statuses:  make(map[types.PlayerID]types.Flags, len(g.players)),
+		clocks:    make(map[types.PlayerID]uint, len(g.players)),
+	}
+	for pid, p := range g.players {
+		if p != nil {
+			undo.statuses[pid] = p.state.status
+			undo.clocks[pid] = p.playerTimer.TimeLeftMs()
+		}
+	}
+	return undo
+}
+
+// Ask the other players to allow the player's last placement to be taken back.
+// Must be called with the game lock held.
+func (g *Game) requestTakeback(player *Player) error {
+	if g.state.status.Has(COMPLETE) {
+		return errors.New("game is over")
+	}
+	if g.undo == nil || g.undo.pid != player.state.pid {
+		return errors.New("nothing to take back")
+	}
+	if g.takeback != nil {
+		return errors.New("takeback already requested")
+	}
+
+	request := &takebackRequest{
+		seq:       g.undo.seq,
+		pid:       player.state.pid,
+		responses: make(map[types.PlayerID]bool),
+	}
+	// only connected humans can answer, and without any there's nobody to agree
+	for pid, p := range g.players {
+		if p != nil && pid != player.state.pid && p.bot == nil && p.state.status.Has(CONNECTED) {
+			request.responses[pid] = false
+		}
+	}
+	if len(request.responses) == 0 {
+		return errors.New("no opponent around to agree to a takeback")
+	}
+	g.takeback = request
+
+	g.socketManager.Broadcast(&types.SocketData{
+		Type: sockets.TAKEBACK_REQUEST,
+		Data: &types.TakebackRequest{PID: player.state.pid},
+	})
+	g.sendGameMessage(fmt.Sprintf("%s asked to take back their last move", player.name))
+
+	g.resolveTakeback()
+	return nil
+}
+
+// Record a player's answer to a pending takeback. Must be called with the game lock held.
+func (g *Game) respondTakeback(player *Player, accept bool) error {
+	if g.takeback == nil {
+		return errors.New("no takeback requested")
+	}
+	if _, ok := g.takeback.responses[player.state.pid]; !ok {
+		return errors.New("not asked to respond")
+	}
+
+	if !accept {
+		g.finishTakeback(false)
+		g.sendGameMessage(fmt.Sprintf("%s declined the takeback", player.name))
+		return nil
+	}
+
+	g.takeback.responses[player.state.pid] = true
+	g.resolveTakeback()
+	return nil
+}
+
+// Apply the takeback once everyone has agreed, or drop it if the game has moved on
+func (g *Game) resolveTakeback() {
+	if g.takeback == nil {
+		return
+	}
+	if g.undo == nil || g.undo.seq != g.takeback.seq || g.state.status.Has(COMPLETE) {
+		g.finishTakeback(false)
+		return
+	}
+	for _, accepted := range g.takeback.responses {
+		if !accepted {
+			return // still waiting
+		}
+	}
+
+	g.rollback(g.undo)
+	g.finishTakeback(true)
+}
+
+func (g *Game) finishTakeback(accepted bool) {
+	g.socketManager.Broadcast(&types.SocketData{
+		Type: sockets.TAKEBACK_RESPONSE,
+		Data: &types.TakebackResult{PID: g.takeback.pid, Accepted: accepted},
+	})
+	g.takeback = nil
+}
+
+// Reverse a placement and restore the turn and clocks from before it was made
+func (g *Game) rollback(undo *undoState) {
+	player := g.players[undo.pid]
+
+	g.state.board.Remove(undo.placement)
+	player.state.pieces.Add(undo.piece)
+
+	for pid, p := range g.players {
+		if p == nil {
+			continue
+		}
+		connected := p.state.status & CONNECTED
+		p.state.status = (undo.statuses[pid] &^ CONNECTED) | connected
+		p.playerTimer.Reset(undo.clocks[pid])
+		p.possiblePlacements = NewPlacementIndex(g.state.board.findPlacements(pid, p.state.pieces)...)
+	}
+
+	g.state.turn = undo.turn
+	g.recordMove(MOVE_TAKEBACK, player, undo.placement.ToSlice())
+	g.undo = nil
+
+	if g.config.TimeControl > 0 {
+		if next := g.players[g.state.turn]; next != nil {
+			next.playerTimer.Start()
+		}
+	}
+
+	// the board clients hold is now out of date
+	g.socketManager.Broadcast(&types.SocketData{Type: sockets.BOARD_STATE, Data: g.state.board.Copy().GetRaw()})
+	g.sendPrivateState(player)
+	g.sendPlayerList()
+	g.sendGameStatus()
+	g.sendGameMessage(fmt.Sprintf("%s took back their last move", player.name))
+	g.saveSnapshot()
+	g.scheduleBots()
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestTakebackRestoresPosition(t *testing.T) {
+	g, err := InitGame("UNDO", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+
+	before := g.state.board.ToString()
+	pieces := g.players[p1].state.pieces.Size()
+	placements := countPlacements(g.players[p1])
+
+	if err := g.PlacePiece(p1, g.players[p1].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if err := g.requestTakeback(g.players[p2]); err == nil {
+		t.Errorf("only the player who moved may ask for a takeback")
+	}
+
+	// the opponent is connected, so they must agree first
+	g.players[p2].state.status.Set(CONNECTED)
+	if err := g.requestTakeback(g.players[p1]); err != nil {
+		t.Fatal(err)
+	}
+	if g.state.turn != p2 {
+		t.Fatalf("takeback applied before the opponent agreed")
+	}
+	if err := g.respondTakeback(g.players[p1], true); err == nil {
+		t.Errorf("the requesting player should not be able to respond")
+	}
+	if err := g.respondTakeback(g.players[p2], true); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.state.turn != p1 {
+		t.Errorf("expected turn to return to player %v, got %v", p1, g.state.turn)
+	}
+	if g.state.board.ToString() != before {
+		t.Errorf("board not restored:\n%s\nexpected:\n%s", g.state.board.ToString(), before)
+	}
+	if g.players[p1].state.pieces.Size() != pieces {
+		t.Errorf("expected %v pieces, got %v", pieces, g.players[p1].state.pieces.Size())
+	}
+	if countPlacements(g.players[p1]) != placements {
+		t.Errorf("expected %v placements, got %v", placements, countPlacements(g.players[p1]))
+	}
+	if last := g.history[len(g.history)-1]; last.Type != MOVE_TAKEBACK {
+		t.Errorf("expected the takeback to be recorded, got %+v", last)
+	}
+	if err := g.requestTakeback(g.players[p1]); err == nil {
+		t.Errorf("a move should only be taken back once")
+	}
+}
+
+func TestTakebackDeclined(t *testing.T) {
+	g, err := InitGame("UNDO", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, TurnBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+	if err := g.PlacePiece(p1, g.players[p1].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.players[p2].state.status.Set(CONNECTED)
+	if err := g.requestTakeback(g.players[p1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.respondTakeback(g.players[p2], false); err != nil {
+		t.Fatal(err)
+	}
+	if g.state.turn != p2 || g.takeback != nil {
+		t.Errorf("declined takeback should leave the game as it was")
+	}
+}
+
+func TestTakebackNeedsSomeoneToAgree(t *testing.T) {
+	g, err := InitGame("UNDO", types.GameConfig{Players: 3, BlockDegree: 4, Density: 1, TurnBased: true, Bots: []types.BotLevel{BOT_GREEDY}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := g.AddPlayer("one", 0xff0000, 0)
+	p2, _ := g.AddPlayer("two", 0x00ff00, 0)
+	if err := g.PlacePiece(p1, g.players[p1].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	// the only other human is disconnected, and the bot can't answer
+	if err := g.requestTakeback(g.players[p1]); err == nil {
+		t.Errorf("takeback requested with nobody to agree to it")
+	}
+	if g.takeback != nil || g.state.turn != p2 {
+		t.Errorf("refused takeback should leave the game as it was")
+	}
+
+	// once they're back, it's up to them
+	g.players[p2].state.status.Set(CONNECTED)
+	if err := g.requestTakeback(g.players[p1]); err != nil {
+		t.Fatal(err)
+	}
+	if g.takeback == nil || g.state.turn != p2 {
+		t.Errorf("takeback applied before the opponent agreed")
+	}
+}
+
+func TestTakebackAgainstBots(t *testing.T) {
+	g, err := InitGame("UNDO", types.GameConfig{Players: 2, BlockDegree: 4, Density: 1, TurnBased: true, Bots: []types.BotLevel{BOT_GREEDY}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hold the bot back so the last move stays the human's
+	g.players[2].bot.thinking = true
+	pid, _ := g.AddPlayer("human", 0xff0000, 0)
+	if err := g.PlacePiece(pid, g.players[pid].possiblePlacements.Any().ToSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if err := g.requestTakeback(g.players[pid]); err == nil {
+		t.Errorf("bots should not be taken to agree to a takeback")
+	}
+	if g.takeback != nil || g.state.turn == pid {
+		t.Errorf("refused takeback should leave the game as it was")
+	}
+}
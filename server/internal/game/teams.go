@@ -0,0 +1,108 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"gobloks/internal/types"
+	"slices"
+	"strings"
+)
+
+func (g *Game) teamsEnabled() bool {
+	return g.config.Teams > 1
+}
+
+// Team of the player in a seat. Seats alternate between teams, so with two
+// teams of two, partners sit opposite each other.
+func (g *Game) teamOf(pid types.PlayerID) uint {
+	if !g.teamsEnabled() {
+		return 0
+	}
+	return uint(pid-1)%g.config.Teams + 1
+}
+
+func validTeams(config types.GameConfig) error {
+	if config.Teams > 1 && config.Players%config.Teams != 0 {
+		return fmt.Errorf("%d players cannot be split into %d even teams", config.Players, config.Teams)
+	}
+	return nil
+}
+
+// First open seat, on the requested team if there is one
+func (g *Game) openSeat(team uint) (types.PlayerID, error) {
+	if team > 0 && (!g.teamsEnabled() || team > g.config.Teams) {
+		return PID_NONE, fmt.Errorf("invalid team %d", team)
+	}
+	for ii := 1; ii <= len(g.players); ii++ {
+		pid := types.PlayerID(ii)
+		if g.players[pid] == nil && (team == 0 || g.teamOf(pid) == team) {
+			return pid, nil
+		}
+	}
+	return PID_NONE, errors.New("team full")
+}
+
+func (g *Game) teamScores(scores map[types.PlayerID]int) map[uint]int {
+	teamScores := make(map[uint]int, g.config.Teams)
+	for pid, score := range scores {
+		teamScores[g.teamOf(pid)] += score
+	}
+	return teamScores
+}
+
+// Teams with the highest combined score. A team where everyone resigned can't win.
+func (g *Game) winningTeams() []uint {
+	teamScores := g.teamScores(g.scores())
+	contending := make(map[uint]bool, len(teamScores))
+	for pid, player := range g.players {
+		if player != nil && player.contending() {
+			contending[g.teamOf(pid)] = true
+		}
+	}
+	teams := make([]uint, 0, len(teamScores))
+	for team := range teamScores {
+		if contending[team] {
+			teams = append(teams, team)
+		}
+	}
+	slices.Sort(teams)
+
+	best := make([]uint, 0, len(teams))
+	for _, team := range teams {
+		if len(best) == 0 || teamScores[team] > teamScores[best[0]] {
+			best = []uint{team}
+		} else if teamScores[team] == teamScores[best[0]] {
+			best = append(best, team)
+		}
+	}
+	return best
+}
+
+// Mark the winning team(s) and describe the result
+func (g *Game) settleTeams() string {
+	winners := g.winningTeams()
+	flag := WINNER
+	if len(winners) > 1 {
+		flag = DRAWN
+	}
+
+	descriptions := make([]string, 0, len(winners))
+	for _, team := range winners {
+		names := make([]string, 0)
+		for ii := 1; ii <= len(g.players); ii++ {
+			pid := types.PlayerID(ii)
+			if player := g.players[pid]; player != nil && player.contending() && g.teamOf(pid) == team {
+				player.state.status.Set(flag)
+				names = append(names, player.name)
+			}
+		}
+		descriptions = append(descriptions, fmt.Sprintf("Team %d (%s)", team, strings.Join(names, ", ")))
+	}
+
+	if len(winners) == 0 {
+		return "Game over! Everyone resigned."
+	} else if len(winners) > 1 {
+		return "Game over! " + strings.Join(descriptions, " and ") + " tied!"
+	}
+	return "Game over! " + descriptions[0] + " wins!"
+}
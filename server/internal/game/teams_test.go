@@ -0,0 +1,111 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestTeamSeating(t *testing.T) {
+	if _, err := InitGame("TEAM", types.GameConfig{Players: 3, BlockDegree: 2, Density: 0.5, Teams: 2}); err == nil {
+		t.Errorf("expected an error for uneven teams")
+	}
+
+	g, err := InitGame("TEAM", types.GameConfig{Players: 4, BlockDegree: 2, Density: 0.5, Teams: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seat := func(team uint, expected types.PlayerID) {
+		t.Helper()
+		pid, err := g.AddPlayer("player", 0xffffff, team)
+		if expected == PID_NONE {
+			if err == nil {
+				t.Errorf("expected joining team %v to fail", team)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pid != expected {
+			t.Errorf("expected seat %v on team %v, got %v", expected, team, pid)
+		}
+	}
+
+	seat(2, 2)
+	seat(2, 4) // partners sit opposite each other
+	seat(2, PID_NONE)
+	seat(3, PID_NONE)
+	seat(1, 1)
+	seat(0, 3)
+
+	list := g.playerList()
+	teams := []uint{1, 1, 2, 2}
+	for ii, player := range list {
+		if player.Team != teams[ii] {
+			t.Errorf("expected teammates listed together, got %+v", list)
+			break
+		}
+	}
+}
+
+func TestTeamScoring(t *testing.T) {
+	g, err := InitGame("TEAM", types.GameConfig{Players: 4, BlockDegree: 2, Density: 0.5, Teams: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ii := 0; ii < 4; ii++ {
+		g.AddPlayer("player", 0xffffff, 0)
+	}
+
+	// team 1 (seats 1 and 3) has placed everything but a domino
+	g.players[1].state.pieces = PieceSet{}
+	g.players[3].state.pieces.Remove(monomino)
+
+	teamScores := g.teamScores(g.scores())
+	if teamScores[1] != -2 || teamScores[2] != -6 {
+		t.Errorf("unexpected team scores %v", teamScores)
+	}
+
+	g.settleTeams()
+	for pid, player := range g.players {
+		won := player.state.status.Has(WINNER)
+		if won != (g.teamOf(pid) == 1) {
+			t.Errorf("player %v on team %v: winner=%v", pid, g.teamOf(pid), won)
+		}
+	}
+}
+
+func TestResignedTeamsCantWin(t *testing.T) {
+	g, err := InitGame("TEAM", types.GameConfig{Players: 4, BlockDegree: 2, Density: 0.5, Teams: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ii := 0; ii < 4; ii++ {
+		g.AddPlayer("player", 0xffffff, 0)
+	}
+
+	// team 1 is ahead, but seat 1 has resigned
+	g.players[1].state.pieces = PieceSet{}
+	g.players[1].state.status.Set(DISABLED | RESIGNED)
+	g.settleTeams()
+	for pid, player := range g.players {
+		won := player.state.status.Has(WINNER)
+		if won != (pid == 3) {
+			t.Errorf("player %v on team %v: winner=%v", pid, g.teamOf(pid), won)
+		}
+	}
+
+	// with the whole team resigned, the win goes to the other
+	for _, player := range g.players {
+		player.state.status.Clear(WINNER)
+	}
+	g.players[3].state.status.Set(DISABLED | RESIGNED)
+	g.settleTeams()
+	for pid, player := range g.players {
+		won := player.state.status.Has(WINNER)
+		if won != (g.teamOf(pid) == 2) {
+			t.Errorf("player %v on team %v: winner=%v", pid, g.teamOf(pid), won)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package game
+
+import "gobloks/internal/types"
+
+// Access tokens carry the generation of the seat they were issued for, and only
+// the current generation is honoured. Moving it on revokes every earlier token,
+// and since it is saved with the game, that lasts across restarts.
+
+func (g *Game) TokenGeneration(pid types.PlayerID) (uint, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return 0, err
+	}
+	return player.tokenGeneration, nil
+}
+
+// Replace the seat's tokens. issue makes the new token for the next generation,
+// and the old tokens are only revoked once it has succeeded.
+func (g *Game) RevokeTokens(pid types.PlayerID, issue func(generation uint) error) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	player, err := g.getPlayer(pid)
+	if err != nil {
+		return err
+	}
+	if err := issue(player.tokenGeneration + 1); err != nil {
+		return err
+	}
+	player.revokeTokens()
+	g.saveSnapshot()
+	return nil
+}
+
+// Must be called with the game lock held.
+func (player *Player) revokeTokens() {
+	player.tokenGeneration++
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"errors"
+	"gobloks/internal/types"
+	"testing"
+	"time"
+)
+
+func TestRevokeTokens(t *testing.T) {
+	g, _ := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+
+	if _, err := g.TokenGeneration(pid + 1); err == nil {
+		t.Error("empty seat has a token generation")
+	}
+
+	// nothing is revoked if the new token can't be made
+	if err := g.RevokeTokens(pid, func(uint) error { return errors.New("no key") }); err == nil {
+		t.Error("expected the issue error back")
+	}
+	if generation, _ := g.TokenGeneration(pid); generation != 0 {
+		t.Errorf("generation moved on without a new token: %d", generation)
+	}
+
+	var issued uint
+	if err := g.RevokeTokens(pid, func(generation uint) error { issued = generation; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if generation, _ := g.TokenGeneration(pid); generation != 1 || issued != 1 {
+		t.Errorf("expected the new token and the seat at generation 1, got %d and %d", issued, generation)
+	}
+
+	secret, _ := g.IssueReclaimSecret(pid)
+	if _, err := g.Reclaim(pid, secret, issueNothing); err != nil {
+		t.Fatal(err)
+	}
+	if generation, _ := g.TokenGeneration(pid); generation != 2 {
+		t.Errorf("reclaiming should revoke the old tokens, at generation %d", generation)
+	}
+
+	// revocations outlive a restart
+	restored, err := RestoreGame(g.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generation, _ := restored.TokenGeneration(pid); generation != 2 {
+		t.Errorf("generation lost in restore: %d", generation)
+	}
+}
+
+func TestDisconnectRevokesTokens(t *testing.T) {
+	g, _ := InitGame("TEST", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	pid, _ := g.AddPlayer("one", 0xffffff, 0)
+
+	// run out the grace period at once
+	g.lock.Lock()
+	player := g.players[pid]
+	g.startConnectionTimer(player)
+	player.connectionTimer.Reset(0)
+	player.connectionTimer.Start()
+	g.lock.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if generation, _ := g.TokenGeneration(pid); generation == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("tokens still valid after the seat was given up")
+}
@@ -0,0 +1,51 @@
+package game
+
+import (
+	"errors"
+	"gobloks/internal/types"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Who can find and join a game
+const (
+	VISIBILITY_PUBLIC   = "public"   // listed in the lobby
+	VISIBILITY_UNLISTED = "unlisted" // joinable by anyone with the invite link
+	VISIBILITY_PRIVATE  = "private"  // invite link and password
+)
+
+// Check the visibility settings, returning the hash of the join password if there is one
+func hashJoinPassword(config types.GameConfig) ([]byte, error) {
+	switch config.Visibility {
+	case VISIBILITY_PRIVATE:
+		if config.Password == "" {
+			return nil, errors.New("a private game needs a password")
+		}
+		return bcrypt.GenerateFromPassword([]byte(config.Password), bcrypt.DefaultCost)
+	case "", VISIBILITY_PUBLIC, VISIBILITY_UNLISTED:
+		if config.Password != "" {
+			return nil, errors.New("only private games take a password")
+		}
+		return nil, nil
+	}
+	return nil, errors.New("invalid visibility " + config.Visibility)
+}
+
+// Whether the game should be shown in the lobby
+func (g *Game) Listed() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.config.Visibility == "" || g.config.Visibility == VISIBILITY_PUBLIC
+}
+
+// Whether the password lets a player in. Games without a password let anyone in.
+func (g *Game) CheckPassword(password string) bool {
+	g.lock.Lock()
+	hash := g.passwordHash
+	g.lock.Unlock()
+
+	if hash == nil {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestPrivateGamePassword(t *testing.T) {
+	g, err := InitGame("test", types.GameConfig{
+		Players: 2, BlockDegree: 3, Density: 0.5,
+		Visibility: VISIBILITY_PRIVATE, Password: "hunter2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Listed() {
+		t.Error("private game should not be listed")
+	}
+	if !g.CheckPassword("hunter2") || g.CheckPassword("hunter3") || g.CheckPassword("") {
+		t.Error("password check doesn't match the configured password")
+	}
+	if g.config.Password != "" || g.Snapshot().Config.Password != "" {
+		t.Error("password kept in the config")
+	}
+
+	// the hash survives a restore
+	restored, err := RestoreGame(g.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.CheckPassword("hunter2") || restored.CheckPassword("") {
+		t.Error("password lost in restore")
+	}
+}
+
+func TestVisibilitySettings(t *testing.T) {
+	cases := []struct {
+		visibility, password string
+		ok                   bool
+	}{
+		{"", "", true},
+		{VISIBILITY_PUBLIC, "", true},
+		{VISIBILITY_UNLISTED, "", true},
+		{VISIBILITY_PRIVATE, "secret", true},
+		{VISIBILITY_PRIVATE, "", false},
+		{VISIBILITY_PUBLIC, "secret", false},
+		{"hidden", "", false},
+	}
+	for _, tc := range cases {
+		g, err := InitGame("test", types.GameConfig{
+			Players: 2, BlockDegree: 3, Density: 0.5,
+			Visibility: tc.visibility, Password: tc.password,
+		})
+		if (err == nil) != tc.ok {
+			t.Errorf("visibility %q password %q: got error %v", tc.visibility, tc.password, err)
+			continue
+		}
+		if err == nil && tc.password == "" && !g.CheckPassword("anything") {
+			t.Errorf("visibility %q: game without a password turned a player away", tc.visibility)
+		}
+	}
+}
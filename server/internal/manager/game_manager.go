@@ -1,30 +1,58 @@
 package manager
 
 import (
+	"cmp"
+	crand "crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"gobloks/internal/authorization"
 	"gobloks/internal/game"
+	"gobloks/internal/storage"
 	"gobloks/internal/types"
 	"math/rand"
+	"slices"
 	"sync"
 	"time"
 )
 
 type GameManager struct {
 	mangagedGames map[types.GameID]*game.Game
+	reserved      map[types.GameID]struct{} // ids of games still being created
 	lock          *sync.Mutex
+	store         storage.Store
+	limits        Limits
 }
 
-func InitGameManager() *GameManager {
+// Limits on what the manager will take on. Zero values mean no limit.
+type Limits struct {
+	MaxGames        int
+	MaxPlayers      uint          // per game
+	CleanupInterval time.Duration // how often stale games are dropped, never if zero
+}
+
+var ErrTooManyGames = errors.New("too many games running, try again later")
+
+// Create a manager, restoring any games kept in the store. A nil store keeps
+// games in memory only.
+func InitGameManager(store storage.Store, limits Limits) *GameManager {
 	manager := &GameManager{
 		make(map[types.GameID]*game.Game, types.MANAGED_GAMES_START_SIZE),
+		make(map[types.GameID]struct{}),
 		&sync.Mutex{},
+		store,
+		limits,
 	}
 
-	go func() {
-		for range time.Tick(time.Hour * 24) {
-			manager.CleanupStale()
-		}
-	}()
+	manager.restoreGames()
+
+	if limits.CleanupInterval > 0 {
+		go func() {
+			for range time.Tick(limits.CleanupInterval) {
+				manager.CleanupStale()
+			}
+		}()
+	}
 
 	return manager
 }
@@ -39,21 +67,112 @@ func createGameID(n uint8) types.GameID {
 	return types.GameID(b)
 }
 
-func (gm *GameManager) CreateGame(config types.GameConfig) types.GameID {
-	var gid types.GameID
+// Long random IDs for games that aren't listed, so they can't be guessed
+func createInviteID() types.GameID {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return types.GameID(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (gm *GameManager) CreateGame(config types.GameConfig) (types.GameID, error) {
+	if gm.limits.MaxPlayers > 0 && config.Players > gm.limits.MaxPlayers {
+		return "", fmt.Errorf("games can have at most %d players", gm.limits.MaxPlayers)
+	}
+
+	// setting up a game is slow, with the password hash and the pieces, so
+	// only the id is taken under the lock
+	gid, err := gm.reserveID(config.Visibility)
+	if err != nil {
+		return "", err
+	}
+	g, err := game.InitGame(gid, config)
+	if err != nil {
+		gm.lock.Lock()
+		delete(gm.reserved, gid)
+		gm.lock.Unlock()
+		return "", err
+	}
+	gm.manage(g)
+
+	return gid, nil
+}
+
+// Claim an unused id for a new game, counting it towards the game limit
+func (gm *GameManager) reserveID(visibility string) (types.GameID, error) {
 	gm.lock.Lock()
 	defer gm.lock.Unlock()
+	if gm.limits.MaxGames > 0 && len(gm.mangagedGames)+len(gm.reserved) >= gm.limits.MaxGames {
+		return "", ErrTooManyGames
+	}
+
+	var gid types.GameID
 	for {
-		gid = createGameID(4)
-		_, ok := gm.mangagedGames[gid]
-		if !ok { // unique game ID
+		if visibility == "" || visibility == game.VISIBILITY_PUBLIC {
+			gid = createGameID(4)
+		} else {
+			gid = createInviteID()
+		}
+		_, managed := gm.mangagedGames[gid]
+		_, reserved := gm.reserved[gid]
+		if !managed && !reserved { // unique game ID
 			break
 		}
 	}
+	gm.reserved[gid] = struct{}{}
+	return gid, nil
+}
+
+func (gm *GameManager) restoreGames() {
+	if gm.store == nil {
+		return
+	}
+
+	snapshots, err := gm.store.Load()
+	if err != nil {
+		fmt.Println("error loading games:", err)
+		return
+	}
+
+	for _, snapshot := range snapshots {
+		g, err := game.RestoreGame(snapshot)
+		if err != nil {
+			fmt.Printf("error restoring game %s: %s\n", snapshot.GID, err)
+			continue
+		}
+		gm.manage(g)
+		fmt.Println("restored game", snapshot.GID)
+	}
+}
 
-	gm.mangagedGames[gid] = game.InitGame(gid, config)
+// Track a game, persisting it whenever it changes. The first save happens
+// before the manager lock is taken.
+func (gm *GameManager) manage(g *game.Game) {
+	if gm.store != nil {
+		g.OnSnapshot(func(snapshot *game.Snapshot) {
+			if err := gm.store.Save(snapshot); err != nil {
+				fmt.Printf("error saving game %s: %s\n", snapshot.GID, err)
+			}
+		})
+		if err := gm.store.Save(g.Snapshot()); err != nil {
+			fmt.Printf("error saving game: %s\n", err)
+		}
+	}
+
+	gm.lock.Lock()
+	defer gm.lock.Unlock()
+	delete(gm.reserved, g.ID())
+	gm.mangagedGames[g.ID()] = g
+}
 
-	return gid
+// The seat's current token generation, so the manager can authenticate requests
+func (gm *GameManager) TokenGeneration(gid types.GameID, pid types.PlayerID) (uint, error) {
+	g, err := gm.FindGame(gid)
+	if err != nil {
+		return 0, err
+	}
+	return g.TokenGeneration(pid)
 }
 
 func (gm *GameManager) FindGame(gid types.GameID) (*game.Game, error) {
@@ -67,26 +186,99 @@ func (gm *GameManager) FindGame(gid types.GameID) (*game.Game, error) {
 	return game, nil
 }
 
-func (gm *GameManager) ListGames(activeOnly bool, page, pageSize int) []types.GameID {
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// A page of the public games matching the query, newest first unless sorted otherwise
+func (gm *GameManager) ListGames(query types.LobbyQuery) *types.Lobby {
 	gm.lock.Lock()
-	defer gm.lock.Unlock()
+	summaries := make([]types.GameSummary, 0, len(gm.mangagedGames))
+	for _, g := range gm.mangagedGames {
+		if g.Listed() {
+			summaries = append(summaries, g.Summary())
+		}
+	}
+	gm.lock.Unlock()
 
-	// TODO: paginate
-	gids := make([]types.GameID, 0, len(gm.mangagedGames))
-	for gid := range gm.mangagedGames {
-		gids = append(gids, gid)
+	games := make([]types.GameSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if lobbyMatches(summary, query) {
+			games = append(games, summary)
+		}
+	}
+
+	slices.SortFunc(games, func(a, b types.GameSummary) int {
+		var order int
+		switch query.Sort {
+		case "active":
+			order = a.LastActive.Compare(b.LastActive)
+		case "open":
+			order = cmp.Compare(a.SeatsOpen, b.SeatsOpen)
+		default:
+			order = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if query.Desc || query.Sort == "" {
+			order = -order
+		}
+		if order == 0 {
+			order = cmp.Compare(a.GID, b.GID) // keep pages stable
+		}
+		return order
+	})
+
+	pageSize := query.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	// compare pages rather than multiplying, which could wrap around for a huge page
+	start := len(games)
+	if query.Page <= uint(len(games))/pageSize {
+		start = int(query.Page * pageSize)
+	}
+	end := min(start+int(pageSize), len(games))
+
+	return &types.Lobby{
+		Games:    games[start:end],
+		Total:    len(games),
+		Page:     query.Page,
+		PageSize: pageSize,
+	}
+}
+
+func lobbyMatches(summary types.GameSummary, query types.LobbyQuery) bool {
+	if query.OpenSeats && summary.SeatsOpen == 0 {
+		return false
+	}
+	switch query.Status {
+	case "waiting":
+		return !summary.Status.Has(game.FULL)
+	case "playing":
+		return summary.Status.Has(game.FULL) && !summary.Status.Has(game.COMPLETE)
+	case "complete":
+		return summary.Status.Has(game.COMPLETE)
 	}
-	return gids
+	return true
 }
 
 func (gm *GameManager) CleanupStale() {
 	gm.lock.Lock()
 	defer gm.lock.Unlock()
 	fmt.Println("cleaning up stale games")
-	for gid := range gm.mangagedGames {
-		if gm.mangagedGames[gid].IsStale() {
+	for gid, g := range gm.mangagedGames {
+		if g.IsStale() {
 			fmt.Println("cleaned up stale game", gid)
+			// stop it first, so nothing still running saves it back
+			g.Close()
 			delete(gm.mangagedGames, gid)
+			authorization.ForgetGame(gid)
+			if gm.store != nil {
+				if err := gm.store.Delete(gid); err != nil {
+					fmt.Printf("error deleting game %s: %s\n", gid, err)
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"errors"
+	"gobloks/internal/game"
+	"gobloks/internal/storage"
+	"gobloks/internal/types"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListGames(t *testing.T) {
+	gm := InitGameManager(nil, Limits{})
+
+	gids := make([]types.GameID, 0, 5)
+	for ii := 0; ii < 5; ii++ {
+		gid, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, TurnBased: ii%2 == 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gids = append(gids, gid)
+		time.Sleep(time.Millisecond) // distinct creation times
+	}
+
+	// fill the first game
+	full, _ := gm.FindGame(gids[0])
+	full.AddPlayer("one", 0xffffff, 0)
+	full.AddPlayer("two", 0xffffff, 0)
+	// half fill the second
+	half, _ := gm.FindGame(gids[1])
+	half.AddPlayer("one", 0xffffff, 0)
+
+	lobby := gm.ListGames(types.LobbyQuery{})
+	if lobby.Total != 5 || len(lobby.Games) != 5 || lobby.Games[0].GID != gids[4] {
+		t.Fatalf("expected all games newest first, got %+v", lobby)
+	}
+
+	open := gm.ListGames(types.LobbyQuery{OpenSeats: true})
+	if open.Total != 4 {
+		t.Errorf("expected 4 games with open seats, got %d", open.Total)
+	}
+	playing := gm.ListGames(types.LobbyQuery{Status: "playing"})
+	if playing.Total != 1 || playing.Games[0].GID != gids[0] || playing.Games[0].SeatsOpen != 0 {
+		t.Errorf("expected only the full game to be playing, got %+v", playing.Games)
+	}
+
+	bySeats := gm.ListGames(types.LobbyQuery{Sort: "open"})
+	if bySeats.Games[0].GID != gids[0] || bySeats.Games[1].GID != gids[1] {
+		t.Errorf("expected games sorted by open seats, got %+v", bySeats.Games)
+	}
+
+	page := gm.ListGames(types.LobbyQuery{Sort: "created", Page: 1, PageSize: 2})
+	if page.Total != 5 || len(page.Games) != 2 || page.Games[0].GID != gids[2] || page.Games[1].GID != gids[3] {
+		t.Errorf("expected the second page of the oldest games, got %+v", page.Games)
+	}
+	if last := gm.ListGames(types.LobbyQuery{Page: 9, PageSize: 2}); len(last.Games) != 0 {
+		t.Errorf("expected an empty page past the end, got %+v", last.Games)
+	}
+	// page * size wraps around to 0
+	if huge := gm.ListGames(types.LobbyQuery{Page: ^uint(0)/2 + 1, PageSize: 2}); len(huge.Games) != 0 {
+		t.Errorf("expected an empty page for a huge page number, got %+v", huge.Games)
+	}
+}
+
+func TestUnlistedGames(t *testing.T) {
+	gm := InitGameManager(nil, Limits{})
+
+	public, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlisted, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Visibility: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Visibility: "private", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(public) != 4 {
+		t.Errorf("expected a short id for a public game, got %q", public)
+	}
+	if len(unlisted) < 20 || len(private) < 20 {
+		t.Errorf("expected long invite ids, got %q and %q", unlisted, private)
+	}
+
+	lobby := gm.ListGames(types.LobbyQuery{})
+	if lobby.Total != 1 || lobby.Games[0].GID != public {
+		t.Errorf("expected only the public game to be listed, got %+v", lobby)
+	}
+	if _, err := gm.FindGame(private); err != nil {
+		t.Errorf("private game not found by its invite id: %v", err)
+	}
+}
+
+func TestManagerLimits(t *testing.T) {
+	gm := InitGameManager(nil, Limits{MaxGames: 2, MaxPlayers: 3})
+
+	if _, err := gm.CreateGame(types.GameConfig{Players: 4, BlockDegree: 3, Density: 0.5}); err == nil {
+		t.Error("created a game with too many players")
+	}
+	for ii := 0; ii < 2; ii++ {
+		if _, err := gm.CreateGame(types.GameConfig{Players: 3, BlockDegree: 3, Density: 0.5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5}); !errors.Is(err, ErrTooManyGames) {
+		t.Errorf("expected ErrTooManyGames, got %v", err)
+	}
+}
+
+// Games are built outside the manager lock, without going over the limit
+func TestConcurrentCreate(t *testing.T) {
+	gm := InitGameManager(nil, Limits{MaxGames: 3})
+
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for ii := 0; ii < 10; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Visibility: game.VISIBILITY_PRIVATE, Password: "hunter2"}
+			if _, err := gm.CreateGame(config); err == nil {
+				created.Add(1)
+			} else if !errors.Is(err, ErrTooManyGames) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created.Load() != 3 || len(gm.mangagedGames) != 3 || len(gm.reserved) != 0 {
+		t.Errorf("expected 3 games and no reservations left, got %d created, %d managed, %d reserved",
+			created.Load(), len(gm.mangagedGames), len(gm.reserved))
+	}
+	if _, err := gm.CreateGame(types.GameConfig{Players: 2, Visibility: "secret"}); err == nil {
+		t.Error("expected an invalid config to fail")
+	}
+	if len(gm.reserved) != 0 {
+		t.Error("failed creation kept its id reserved")
+	}
+}
+
+func TestCleanupStale(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := game.InitGame("OLDG", types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	g.AddPlayer("one", 0xffffff, 0)
+	snap := g.Snapshot()
+	snap.LastActive = time.Now().Add(-30 * 24 * time.Hour)
+	if err := store.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	gm := InitGameManager(store, Limits{})
+	restored, err := gm.FindGame("OLDG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gm.CleanupStale()
+
+	if _, err := gm.FindGame("OLDG"); err == nil {
+		t.Error("stale game still managed")
+	}
+	// anything the game still does must not bring it back
+	restored.IssueReclaimSecret(1)
+	if snapshots, _ := store.Load(); len(snapshots) != 0 {
+		t.Errorf("stale game saved again after cleanup")
+	}
+}
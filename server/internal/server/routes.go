@@ -1,15 +1,21 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"gobloks/internal/authorization"
+	"gobloks/internal/game"
 	"gobloks/internal/manager"
 	"gobloks/internal/types"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Carries a private game's password on requests that have no body to put it in
+const PasswordHeader = "Game-Password"
+
 func createGame(c *gin.Context) {
 	var config types.GameConfig
 
@@ -20,17 +26,28 @@ func createGame(c *gin.Context) {
 	}
 
 	gm := c.MustGet("manager").(*manager.GameManager)
-	gid := gm.CreateGame(config)
+	gid, err := gm.CreateGame(config)
+	if errors.Is(err, manager.ErrTooManyGames) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, err.Error())
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
 
 	c.IndentedJSON(http.StatusCreated, gid)
 }
 
 func listGames(c *gin.Context) {
-	fmt.Println("list games")
-	gm := c.MustGet("manager").(*manager.GameManager)
-	games := gm.ListGames(true, 0, 0)
+	var query types.LobbyQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
 
-	c.IndentedJSON(http.StatusOK, games)
+	gm := c.MustGet("manager").(*manager.GameManager)
+	c.IndentedJSON(http.StatusOK, gm.ListGames(query))
 }
 
 func joinGame(c *gin.Context) {
@@ -54,13 +71,116 @@ func joinGame(c *gin.Context) {
 		return
 	}
 
-	pid, err := gs.AddPlayer(config.Name, config.Color)
+	if !gs.CheckPassword(config.Password) {
+		c.AbortWithStatusJSON(http.StatusForbidden, "wrong password")
+		return
+	}
+
+	pid, err := gs.AddPlayer(config.Name, config.Color, config.Team)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusConflict, err)
 		return
 	}
 
-	token, err := authorization.CreateAccessToken(pid, types.GameID(gid), 3600)
+	secret, err := gs.IssueReclaimSecret(pid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	generation, err := gs.TokenGeneration(pid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+		return
+	}
+	token, err := authorization.CreateAccessToken(pid, types.GameID(gid), generation, authorization.AccessTokenTTL)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Writer.Header().Set("Access-Token", token)
+	c.JSON(http.StatusOK, types.Seat{PID: pid, Reclaim: secret})
+}
+
+// Give a player their seat back on a new device, locking out their old tokens
+func reclaimSeat(c *gin.Context) {
+	gid, ok := c.GetQuery("game")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, "no game provided")
+		return
+	}
+
+	var request types.ReclaimRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err)
+		return
+	}
+
+	gm := c.MustGet("manager").(*manager.GameManager)
+	gs, err := gm.FindGame(types.GameID(gid))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return
+	}
+
+	var token string
+	var tokenErr error
+	secret, err := gs.Reclaim(request.PID, request.Secret, func(generation uint) error {
+		token, tokenErr = authorization.CreateAccessToken(request.PID, types.GameID(gid), generation, authorization.AccessTokenTTL)
+		return tokenErr
+	})
+	if tokenErr != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, tokenErr)
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Writer.Header().Set("Access-Token", token)
+	c.JSON(http.StatusOK, types.Seat{PID: request.PID, Reclaim: secret})
+}
+
+func spectateGame(c *gin.Context) {
+	gs, ok := findVisibleGame(c)
+	if !ok {
+		return
+	}
+
+	token, err := authorization.CreateSpectatorToken(gs.ID(), authorization.AccessTokenTTL)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Writer.Header().Set("Access-Token", token)
+}
+
+// Swap the caller's token for a fresh one, so long games outlive the first token
+func refreshToken(c *gin.Context) {
+	g := c.MustGet("manager").(*manager.GameManager)
+	gid := c.MustGet("gid").(types.GameID)
+	gs, err := g.FindGame(gid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return
+	}
+
+	// the old token stops working once the new one is out
+	var token string
+	claims := authorization.GetClaims(c)
+	if claims.Spectator {
+		token, err = authorization.CreateSpectatorToken(gid, authorization.AccessTokenTTL)
+		if err == nil {
+			authorization.Revoke(gid, claims.Id, claims.Expires())
+		}
+	} else {
+		err = gs.RevokeTokens(claims.PlayerID, func(generation uint) (err error) {
+			token, err = authorization.CreateAccessToken(claims.PlayerID, gid, generation, authorization.AccessTokenTTL)
+			return err
+		})
+	}
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
 		return
@@ -69,6 +189,38 @@ func joinGame(c *gin.Context) {
 	c.Writer.Header().Set("Access-Token", token)
 }
 
+// Public keys for verifying tokens outside gobloks
+func getSigningKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, authorization.PublicKeys())
+}
+
+func getReplay(c *gin.Context) {
+	gs, ok := findVisibleGame(c)
+	if !ok {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gs.Replay())
+}
+
+func getAnalysis(c *gin.Context) {
+	gs, ok := findVisibleGame(c)
+	if !ok {
+		return
+	}
+
+	analysis, err := gs.Analysis()
+	if errors.Is(err, game.ErrAnalysisPending) {
+		c.AbortWithStatusJSON(http.StatusAccepted, gin.H{"message": err.Error()})
+		return
+	} else if err != nil {
+		abortWithGameError(c, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, analysis)
+}
+
 func placePiece(c *gin.Context) {
 	g := c.MustGet("manager").(*manager.GameManager)
 	gid := c.MustGet("gid").(types.GameID)
@@ -89,8 +241,7 @@ func placePiece(c *gin.Context) {
 
 	err = gs.PlacePiece(pid, placement)
 	if err != nil {
-		fmt.Println(err)
-		c.AbortWithStatusJSON(http.StatusConflict, "invalid placement")
+		abortWithGameError(c, err)
 		return
 	}
 }
@@ -104,12 +255,117 @@ func getHint(c *gin.Context) {
 		return
 	}
 
+	level := game.HINT_CORNER
+	if param, ok := c.GetQuery("level"); ok {
+		parsed, err := strconv.ParseUint(param, 10, 8)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, "invalid hint level")
+			return
+		}
+		level = types.HintLevel(parsed)
+	}
+
 	pid := c.MustGet("pid").(types.PlayerID)
-	hint, err := gs.GetHint(pid)
+	hint, err := gs.GetHint(pid, level)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusConflict, err)
+		abortWithGameError(c, err)
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, hint)
 }
+
+func getMoves(c *gin.Context) {
+	g := c.MustGet("manager").(*manager.GameManager)
+	gid := c.MustGet("gid").(types.GameID)
+	gs, err := g.FindGame(gid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return
+	}
+
+	var query types.MovesQuery
+	err = c.ShouldBindQuery(&query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pid := c.MustGet("pid").(types.PlayerID)
+	moves, err := gs.LegalMoves(pid, query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, moves)
+}
+
+func passTurn(c *gin.Context) {
+	g := c.MustGet("manager").(*manager.GameManager)
+	gid := c.MustGet("gid").(types.GameID)
+	gs, err := g.FindGame(gid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return
+	}
+
+	pid := c.MustGet("pid").(types.PlayerID)
+	err = gs.Pass(pid)
+	if err != nil {
+		abortWithGameError(c, err)
+		return
+	}
+}
+
+func resign(c *gin.Context) {
+	g := c.MustGet("manager").(*manager.GameManager)
+	gid := c.MustGet("gid").(types.GameID)
+	gs, err := g.FindGame(gid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return
+	}
+
+	pid := c.MustGet("pid").(types.PlayerID)
+	err = gs.Resign(pid)
+	if err != nil {
+		abortWithGameError(c, err)
+		return
+	}
+}
+
+// The game named in the query, if the caller may look at it. Private games are
+// closed without the password in the PasswordHeader, to spectators and to
+// anything read about them.
+func findVisibleGame(c *gin.Context) (*game.Game, bool) {
+	gid, ok := c.GetQuery("game")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, "no game provided")
+		return nil, false
+	}
+
+	gm := c.MustGet("manager").(*manager.GameManager)
+	gs, err := gm.FindGame(types.GameID(gid))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, fmt.Sprintf("no game %s", gid))
+		return nil, false
+	}
+
+	// never in the query, which ends up in the access log
+	if !gs.CheckPassword(c.GetHeader(PasswordHeader)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, "wrong password")
+		return nil, false
+	}
+	return gs, true
+}
+
+// Reject the request, with a code the client can act on when the game gives one
+func abortWithGameError(c *gin.Context, err error) {
+	var gameErr *game.GameError
+	if errors.As(err, &gameErr) {
+		c.AbortWithStatusJSON(http.StatusConflict, gameErr)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": err.Error()})
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
-	"fmt"
 	"gobloks/internal/authorization"
+	"gobloks/internal/config"
+	"gobloks/internal/game"
 	"gobloks/internal/manager"
+	"gobloks/internal/storage"
+	"log"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,28 +20,26 @@ func ApiMiddleware(m *manager.GameManager) gin.HandlerFunc {
 	}
 }
 
-func CORSMiddleware(production bool) gin.HandlerFunc {
-	const FRONTEND_ORIGIN string = "http://209.97.144.150"
-
-	var allowedOrigins string
-	if production {
-		allowedOrigins = FRONTEND_ORIGIN
-	} else {
-		allowedOrigins = "*"
-	}
+// Routes any site may call, since they hold nothing secret
+var openRoutes = []string{"/.well-known/jwks.json"}
 
+// Allow cross origin calls from the given origins, or from anywhere if there are none.
+// Requests without an Origin don't come from a browser page, so aren't checked.
+func CORSMiddleware(origins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if production {
-			origin := c.Request.Header.Get("Origin")
-			if origin != FRONTEND_ORIGIN {
+		allowed := "*"
+		origin := c.Request.Header.Get("Origin")
+		if len(origins) > 0 && origin != "" && !slices.Contains(openRoutes, c.Request.URL.Path) {
+			if !slices.Contains(origins, origin) {
 				c.AbortWithStatus(403)
 				return
 			}
+			allowed = origin
 		}
 
-		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigins)
+		c.Writer.Header().Set("Access-Control-Allow-Origin", allowed)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Access-Token")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Access-Token, Game-Password")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT")
 		c.Writer.Header().Set("Access-Control-Expose-Headers", "Access-Token")
 
@@ -49,33 +52,69 @@ func CORSMiddleware(production bool) gin.HandlerFunc {
 	}
 }
 
-func Start(port uint, production bool) {
-	authorization.SetupKeys()
+func Start(cfg *config.Config) {
+	if err := authorization.SetupKeys(authorization.KeyOptions{
+		Dir:       cfg.KeyDir,
+		Algorithm: cfg.KeyAlgorithm,
+		Rotate:    cfg.RotateKey,
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	var store storage.Store
+	if cfg.StorageDir != "" {
+		fileStore, err := storage.NewFileStore(cfg.StorageDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = fileStore
+	}
 
-	globalGameManager := manager.InitGameManager()
+	game.ReconnectGrace = time.Duration(cfg.ReconnectGrace)
+	globalGameManager := manager.InitGameManager(store, manager.Limits{
+		MaxGames:        cfg.MaxGames,
+		MaxPlayers:      cfg.MaxPlayers,
+		CleanupInterval: time.Duration(cfg.CleanupInterval),
+	})
 
-	if production {
+	if cfg.Production {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	router := newRouter(globalGameManager, cfg)
+	router.Run(cfg.Listen)
+}
+
+func newRouter(gm *manager.GameManager, cfg *config.Config) *gin.Engine {
 	router := gin.Default()
 	router.SetTrustedProxies(nil)
 	router.Use(
-		ApiMiddleware(globalGameManager),
-		CORSMiddleware(production),
-		authorization.AuthMiddleware([]gin.HandlerFunc{
-			createGame,
-			joinGame,
-			listGames,
-		}),
+		ApiMiddleware(gm),
+		CORSMiddleware(cfg.AllowedOrigins),
 	)
 
-	router.POST("/create", createGame)
-	router.GET("/list", listGames)
-	router.POST("/join", joinGame)
-	router.PUT("/place", placePiece)
-	router.GET("/hint", getHint)
-	router.GET("/ws", handleWebsocket)
+	// open to anyone, for finding and joining games
+	public := router.Group("/")
+	public.POST("/create", createGame)
+	public.GET("/list", listGames)
+	public.POST("/join", joinGame)
+	public.POST("/reclaim", reclaimSeat)
+	public.GET("/replay", getReplay)
+	public.GET("/spectate", spectateGame)
+	public.GET("/analysis", getAnalysis)
+	public.GET("/.well-known/jwks.json", getSigningKeys)
+
+	// players and spectators, acting on the game their token is for
+	authenticated := router.Group("/", authorization.Authenticate(gm))
+	authenticated.POST("/refresh", refreshToken)
+	authenticated.GET("/ws", websocketHandler(newUpgrader(cfg)))
+
+	players := authenticated.Group("/", authorization.PlayerOnly())
+	players.PUT("/place", placePiece)
+	players.GET("/hint", getHint)
+	players.GET("/moves", getMoves)
+	players.PUT("/pass", passTurn)
+	players.PUT("/resign", resign)
 
-	router.Run(fmt.Sprintf("0.0.0.0:%d", port))
+	return router
 }
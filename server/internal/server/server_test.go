@@ -0,0 +1,197 @@
+package server
+
+import (
+	"gobloks/internal/authorization"
+	"gobloks/internal/config"
+	"gobloks/internal/manager"
+	"gobloks/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestRouteGroups(t *testing.T) {
+	t.Setenv(authorization.KeyEnv, "")
+	if err := authorization.SetupKeys(authorization.KeyOptions{Dir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	gm := manager.InitGameManager(nil, manager.Limits{})
+	gid, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(gm, config.Default())
+
+	gs, _ := gm.FindGame(gid)
+	if _, err := gs.AddPlayer("one", 0xffffff, 0); err != nil {
+		t.Fatal(err)
+	}
+	player, _ := authorization.CreateAccessToken(1, gid, 0, 60)
+	unseated, _ := authorization.CreateAccessToken(2, gid, 0, 60)
+	spectator, _ := authorization.CreateSpectatorToken(gid, 60)
+
+	cases := []struct {
+		method, path, token string
+		status              int
+	}{
+		{http.MethodGet, "/list", "", http.StatusOK},
+		{http.MethodGet, "/replay?game=" + string(gid), "", http.StatusOK},
+		{http.MethodGet, "/.well-known/jwks.json", "", http.StatusOK},
+		{http.MethodPut, "/pass", "", http.StatusUnauthorized},
+		{http.MethodPost, "/refresh", "", http.StatusUnauthorized},
+		{http.MethodPut, "/pass", spectator, http.StatusForbidden},
+		{http.MethodGet, "/moves", spectator, http.StatusForbidden},
+		{http.MethodPost, "/refresh", spectator, http.StatusOK},
+		{http.MethodGet, "/moves", player, http.StatusOK},
+		{http.MethodGet, "/moves", unseated, http.StatusUnauthorized}, // seat not taken yet
+		{http.MethodPost, "/refresh", player, http.StatusOK},
+		{http.MethodGet, "/moves", player, http.StatusUnauthorized}, // replaced by the refresh
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set(authorization.AccessTokenHeader, tc.token)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		if res.Code != tc.status {
+			t.Errorf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.status, res.Code, res.Body)
+		}
+	}
+}
+
+func TestPrivateGameReads(t *testing.T) {
+	t.Setenv(authorization.KeyEnv, "")
+	if err := authorization.SetupKeys(authorization.KeyOptions{Dir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	gm := manager.InitGameManager(nil, manager.Limits{})
+	gid, err := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5, Visibility: "private", Password: "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(gm, config.Default())
+
+	cases := []struct {
+		path, password string
+		status         int
+	}{
+		{"/replay", "", http.StatusForbidden},
+		{"/analysis", "", http.StatusForbidden},
+		{"/spectate", "", http.StatusForbidden},
+		{"/replay", "guess", http.StatusForbidden},
+		{"/replay", "hunter2", http.StatusOK},
+		{"/spectate", "hunter2", http.StatusOK},
+		{"/analysis", "hunter2", http.StatusConflict}, // readable, but not over yet
+		{"/replay?password=hunter2", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		path := tc.path + "?game=" + string(gid)
+		if strings.Contains(tc.path, "?") {
+			path = tc.path + "&game=" + string(gid)
+		}
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if tc.password != "" {
+			req.Header.Set(PasswordHeader, tc.password)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		if res.Code != tc.status {
+			t.Errorf("%s with password %q: expected %d, got %d: %s", tc.path, tc.password, tc.status, res.Code, res.Body)
+		}
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.AllowedOrigins = []string{"https://gobloks.example"}
+	cfg.CheckWebsocketOrigin = true
+	router := newRouter(manager.InitGameManager(nil, manager.Limits{}), cfg)
+
+	for origin, status := range map[string]int{
+		"https://gobloks.example": http.StatusOK,
+		"https://evil.example":    http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set("Origin", origin)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		if res.Code != status {
+			t.Errorf("%s: expected %d, got %d", origin, status, res.Code)
+		}
+		if status == http.StatusOK && res.Header().Get("Access-Control-Allow-Origin") != origin {
+			t.Errorf("%s: allowed origin not echoed back", origin)
+		}
+	}
+
+	// the public keys are for anyone, and requests from outside a browser have no origin
+	for _, open := range []struct{ path, origin string }{
+		{"/.well-known/jwks.json", "https://evil.example"},
+		{"/.well-known/jwks.json", ""},
+		{"/list", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, open.path, nil)
+		if open.origin != "" {
+			req.Header.Set("Origin", open.origin)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Errorf("%s from %q: expected 200, got %d", open.path, open.origin, res.Code)
+		}
+	}
+
+	upgrader := newUpgrader(cfg)
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if upgrader.CheckOrigin(req) {
+		t.Error("websocket from a foreign origin allowed")
+	}
+	req.Header.Set("Origin", "https://gobloks.example")
+	if !upgrader.CheckOrigin(req) {
+		t.Error("websocket from an allowed origin refused")
+	}
+	req.Header.Del("Origin")
+	if !upgrader.CheckOrigin(req) {
+		t.Error("websocket without an origin refused")
+	}
+}
+
+func TestRefusedSocketIsClosed(t *testing.T) {
+	t.Setenv(authorization.KeyEnv, "")
+	if err := authorization.SetupKeys(authorization.KeyOptions{Dir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	gm := manager.InitGameManager(nil, manager.Limits{})
+	gid, _ := gm.CreateGame(types.GameConfig{Players: 2, BlockDegree: 3, Density: 0.5})
+	gs, _ := gm.FindGame(gid)
+	pid, _ := gs.AddPlayer("one", 0xffffff, 0)
+	token, _ := authorization.CreateAccessToken(pid, gid, 0, 60)
+	gs.Close()
+
+	server := httptest.NewServer(newRouter(gm, config.Default()))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + authorization.AccessTokenQuery + "=" + token
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("expected the refused socket to be closed with a reason, got %v", err)
+	}
+}
@@ -2,44 +2,79 @@ package server
 
 import (
 	"fmt"
+	"gobloks/internal/authorization"
+	"gobloks/internal/config"
 	"gobloks/internal/manager"
 	"gobloks/internal/types"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all connections
-		return true
-	},
+// Websockets are checked against the allowed origins if asked, or the host if none are listed
+func newUpgrader(cfg *config.Config) *websocket.Upgrader {
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	switch {
+	case !cfg.CheckWebsocketOrigin:
+		upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	case len(cfg.AllowedOrigins) > 0:
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			// like the API, clients outside a browser send no origin
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(cfg.AllowedOrigins, origin)
+		}
+	}
+	return upgrader
 }
 
-func handleWebsocket(c *gin.Context) {
-	g := c.MustGet("manager").(*manager.GameManager)
-	gid := c.MustGet("gid").(types.GameID)
-	gs, err := g.FindGame(gid)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "access denied"})
-		return
-	}
+func websocketHandler(upgrader *websocket.Upgrader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		g := c.MustGet("manager").(*manager.GameManager)
+		gid := c.MustGet("gid").(types.GameID)
+		gs, err := g.FindGame(gid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "access denied"})
+			return
+		}
 
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		conn.Close()
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// the upgrader has already sent the error response
+			c.Abort()
+			return
+		}
 
-	fmt.Println("Connecting socket")
+		fmt.Println("Connecting socket")
 
-	pid := c.MustGet("pid").(types.PlayerID)
+		if authorization.GetClaims(c).Spectator {
+			err = gs.ConnectSpectator(conn)
+		} else {
+			pid := c.MustGet("pid").(types.PlayerID)
+			fmt.Println("PID: ", pid)
+			err = gs.ConnectSocket(conn, pid)
+		}
+		if err != nil {
+			refuseSocket(conn, err)
+		}
+	}
+}
 
-	fmt.Println("PID: ", pid)
+// Close frames hold at most 125 bytes, two of which are the code
+const maxCloseReason = 123
 
-	go gs.ConnectSocket(conn, pid)
+// Tell the client why the game turned its connection away, then drop it
+func refuseSocket(conn *websocket.Conn, err error) {
+	reason := err.Error()
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.Close()
 }
@@ -1,6 +1,7 @@
 package sockets
 
 import (
+	"encoding/json"
 	"fmt"
 	"gobloks/internal/types"
 	"gobloks/internal/utilities"
@@ -17,6 +18,12 @@ const (
 	CHAT_MESSAGE
 	GAME_STATUS
 	BOARD_UPDATE
+	REPLAY
+	TAKEBACK_REQUEST
+	TAKEBACK_RESPONSE
+	PASS
+	RESIGN
+	LEGAL_MOVES
 )
 
 type Connection struct {
@@ -64,6 +71,24 @@ func (s *SocketManager) Disconnect(conn *Connection) {
 	s.activeConnections.Remove(conn)
 }
 
+// Disconnect and close the socket, ending any reads on it
+func (s *SocketManager) Close(conn *Connection) {
+	s.Disconnect(conn)
+	conn.socket.Close()
+}
+
+// Close every connection, such as when the game is going away
+func (s *SocketManager) CloseAll() {
+	s.mu.Lock()
+	conns := s.activeConnections.ToSlice()
+	s.activeConnections.Clear()
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.socket.Close()
+	}
+}
+
 func (s *SocketManager) Send(conn *Connection, out *types.SocketData) {
 	go conn.send(out)
 }
@@ -72,6 +97,15 @@ func (s *SocketManager) Recv(conn *Connection, in *types.SocketData) error {
 	return conn.recv(in)
 }
 
+// Decode the payload of a received message into out
+func DecodeData(in *types.SocketData, out any) error {
+	raw, err := json.Marshal(in.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
 // func (s *SocketManager) Broadcast(message *types.ChatMessage) {
 func (s *SocketManager) Broadcast(out *types.SocketData) {
 	s.mu.Lock()
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gobloks/internal/game"
+	"gobloks/internal/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Persists game snapshots so games survive a server restart
+type Store interface {
+	Save(snapshot *game.Snapshot) error
+	Load() ([]*game.Snapshot, error)
+	Delete(gid types.GameID) error
+}
+
+const snapshotExt = ".json"
+
+// Stores each game as a JSON file in a directory
+type FileStore struct {
+	dir string
+	mu  *sync.Mutex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir, &sync.Mutex{}}, nil
+}
+
+func (fs *FileStore) path(gid types.GameID) string {
+	return filepath.Join(fs.dir, string(gid)+snapshotExt)
+}
+
+func (fs *FileStore) Save(snapshot *game.Snapshot) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a crash never leaves a partial snapshot
+	tmp, err := os.CreateTemp(fs.dir, "."+string(snapshot.GID)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.path(snapshot.GID))
+}
+
+func (fs *FileStore) Load() ([]*game.Snapshot, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	entries, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*game.Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != snapshotExt {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(fs.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var snapshot game.Snapshot
+		if err = json.Unmarshal(data, &snapshot); err != nil {
+			fmt.Printf("skipping unreadable snapshot %s: %s\n", name, err)
+			continue
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	return snapshots, nil
+}
+
+func (fs *FileStore) Delete(gid types.GameID) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	err := os.Remove(fs.path(gid))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
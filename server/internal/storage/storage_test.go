@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"gobloks/internal/game"
+	"gobloks/internal/types"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, gid := range []types.GameID{"AAAA", "BBBB"} {
+		err := store.Save(&game.Snapshot{GID: gid, Config: types.GameConfig{Players: 2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// saving again replaces the old snapshot
+	if err := store.Save(&game.Snapshot{GID: "AAAA", Config: types.GameConfig{Players: 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %v", len(snapshots))
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.GID == "AAAA" && snapshot.Config.Players != 4 {
+			t.Errorf("expected the latest snapshot to be loaded")
+		}
+	}
+
+	if err := store.Delete("AAAA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("AAAA"); err != nil {
+		t.Errorf("deleting a missing game should not fail: %s", err)
+	}
+	snapshots, _ = store.Load()
+	if len(snapshots) != 1 || snapshots[0].GID != "BBBB" {
+		t.Errorf("expected only BBBB to remain")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 const (
 	PLAYER_MASK Owner = 0x0000ffff
+	SPECTATOR   Owner = (1 << 28)
 	VACANT      Owner = (1 << 29)
 	ORIGIN      Owner = (1 << 30)
 	RESERVED    Owner = (1 << 31)
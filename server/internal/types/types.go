@@ -1,5 +1,9 @@
 package types
 
+import (
+	"time"
+)
+
 type Direction int
 type Axis int
 type Owner uint32
@@ -8,6 +12,10 @@ type GameID string
 
 type Flags uint32
 type SocketDataType uint32
+type BotLevel uint8
+type MoveType uint8
+type ScoringMode uint8
+type HintLevel uint8
 
 type SocketData struct {
 	Type SocketDataType `json:"type"`
@@ -20,21 +28,47 @@ type PublicPiece struct {
 }
 
 type GameConfig struct {
-	Players     uint    `json:"players" binding:"required,gte=1,lte=65536"`
-	BlockDegree uint8   `json:"degree" binding:"required,gte=1,lte=8"`
-	Density     float64 `json:"density"`
-	TurnBased   bool    `json:"turns"`
-	TimeControl uint    `json:"timeSeconds"`
-	TimeBonus   uint    `json:"timeBonus"`
-	Hints       uint    `json:"hints"`
+	Players       uint          `json:"players" binding:"required,gte=1,lte=65536"`
+	BlockDegree   uint8         `json:"degree" binding:"omitempty,gte=1,lte=8"`
+	MinDegree     uint8         `json:"minDegree" binding:"lte=8"`
+	PieceSet      string        `json:"pieceSet" binding:"omitempty,oneof=classic pentominoes"`
+	Pieces        []PublicPiece `json:"pieces,omitempty" binding:"omitempty,max=256"`
+	Density       float64       `json:"density"`
+	TurnBased     bool          `json:"turns"`
+	TimeControl   uint          `json:"timeSeconds"`
+	TimeBonus     uint          `json:"timeBonus"`
+	Hints         uint          `json:"hints"`
+	HintCosts     HintCosts     `json:"hintCosts"`
+	Bots          []BotLevel    `json:"bots" binding:"omitempty,dive,gte=1,lte=3"`
+	Scoring       ScoringMode   `json:"scoring" binding:"lte=1"`
+	Teams         uint          `json:"teams"`
+	SpectatorChat bool          `json:"spectatorChat"`
+	Shape         string        `json:"shape" binding:"omitempty,oneof=circle square hexagon custom"`
+	Mask          []string      `json:"mask,omitempty"`
+	Visibility    string        `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	Password      string        `json:"password,omitempty" binding:"max=72"`
 }
 
 type PlayerConfig struct {
-	PID    PlayerID `json:"pid"`
-	Name   string   `json:"name" binding:"required,max=32"`
-	Color  uint     `json:"color" binding:"required,gt=0,lte=16777215"`
-	Status Flags    `json:"status"`
-	Time   uint     `json:"timeMs"`
+	PID      PlayerID `json:"pid"`
+	Name     string   `json:"name" binding:"required,max=32"`
+	Color    uint     `json:"color" binding:"required,gt=0,lte=16777215"`
+	Status   Flags    `json:"status"`
+	Time     uint     `json:"timeMs"`
+	Team     uint     `json:"team"`
+	Password string   `json:"password,omitempty"`
+}
+
+// A seat handed out by join or reclaim. The reclaim secret gets the seat back
+// if the access token is lost.
+type Seat struct {
+	PID     PlayerID `json:"pid"`
+	Reclaim string   `json:"reclaim"`
+}
+
+type ReclaimRequest struct {
+	PID    PlayerID `json:"pid" binding:"required"`
+	Secret string   `json:"secret" binding:"required"`
 }
 
 type ChatMessage struct {
@@ -49,11 +83,141 @@ type PrivateGameState struct {
 }
 
 type PublicGameState struct {
-	Turn   PlayerID `json:"turn"`
-	Status Flags    `json:"status"`
+	Turn       PlayerID         `json:"turn"`
+	Status     Flags            `json:"status"`
+	Scores     map[PlayerID]int `json:"scores,omitempty"`
+	TeamScores map[uint]int     `json:"teamScores,omitempty"`
 }
 
 type BoardUpdate struct {
 	Owner     `json:"owner"`
 	Placement `json:"placement"`
 }
+
+type Move struct {
+	Seq       uint      `json:"seq"`
+	Type      MoveType  `json:"type"`
+	PID       PlayerID  `json:"pid"`
+	Placement Placement `json:"placement,omitempty"`
+	Time      uint      `json:"timeMs"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type Replay struct {
+	Board   [][]Owner      `json:"board"`
+	Players []PlayerConfig `json:"players"`
+	Moves   []Move         `json:"moves"`
+}
+
+type TakebackRequest struct {
+	PID PlayerID `json:"pid"`
+}
+
+type TakebackResponse struct {
+	Accept bool `json:"accept"`
+}
+
+type TakebackResult struct {
+	PID      PlayerID `json:"pid"`
+	Accepted bool     `json:"accepted"`
+}
+
+// Filters for a legal moves request. The anchor needs both coordinates.
+type MovesQuery struct {
+	Piece  uint64 `json:"piece" form:"piece"`
+	X      *int   `json:"x" form:"x"`
+	Y      *int   `json:"y" form:"y"`
+	Offset uint   `json:"offset" form:"offset"`
+	Limit  uint   `json:"limit" form:"limit" binding:"lte=1000"`
+}
+
+type LegalMove struct {
+	Piece     uint64    `json:"piece"`
+	Placement Placement `json:"placement"`
+}
+
+type LegalMoves struct {
+	Total  int         `json:"total"`
+	Offset uint        `json:"offset"`
+	Moves  []LegalMove `json:"moves"`
+}
+
+// Hints spent for each level of hint. Zero means the default cost.
+type HintCosts struct {
+	Corner    uint `json:"corner"`
+	Piece     uint `json:"piece"`
+	Placement uint `json:"placement"`
+}
+
+type Hint struct {
+	Level     HintLevel    `json:"level"`
+	Corner    *Point       `json:"corner,omitempty"`
+	Piece     *PublicPiece `json:"piece,omitempty"`
+	Placement Placement    `json:"placement,omitempty"`
+	Remaining uint         `json:"remaining"`
+}
+
+// What happened on one placement, and what the engine would have played instead
+type TurnAnalysis struct {
+	Seq       uint             `json:"seq"`
+	PID       PlayerID         `json:"pid"`
+	Placement Placement        `json:"placement"`
+	Best      Placement        `json:"best,omitempty"`
+	Options   int              `json:"options"`
+	Cutoff    map[PlayerID]int `json:"cutoff,omitempty"`
+}
+
+// The placement that took the most options away from a player
+type Cutoff struct {
+	Seq  uint     `json:"seq"`
+	By   PlayerID `json:"by"`
+	Lost int      `json:"lost"`
+}
+
+type PlayerAnalysis struct {
+	PID         PlayerID `json:"pid"`
+	Mobility    []int    `json:"mobility"`
+	Territory   int      `json:"territory"`
+	Corners     int      `json:"corners"`
+	WorstCutoff *Cutoff  `json:"worstCutoff,omitempty"`
+}
+
+type Analysis struct {
+	Players []PlayerAnalysis `json:"players"`
+	Turns   []TurnAnalysis   `json:"turns"`
+}
+
+// Lobby filters. Pages are numbered from 0.
+type LobbyQuery struct {
+	OpenSeats bool   `form:"open"`
+	Status    string `form:"status" binding:"omitempty,oneof=waiting playing complete"`
+	Sort      string `form:"sort" binding:"omitempty,oneof=created active open"`
+	Desc      bool   `form:"desc"`
+	Page      uint   `form:"page"`
+	PageSize  uint   `form:"pageSize" binding:"lte=100"`
+}
+
+type GameSummary struct {
+	GID         GameID    `json:"gid"`
+	Players     uint      `json:"players"`
+	Degree      uint8     `json:"degree"`
+	PieceSet    string    `json:"pieceSet,omitempty"`
+	Shape       string    `json:"shape,omitempty"`
+	TurnBased   bool      `json:"turns"`
+	TimeControl uint      `json:"timeSeconds"`
+	TimeBonus   uint      `json:"timeBonus"`
+	Teams       uint      `json:"teams,omitempty"`
+	Visibility  string    `json:"visibility,omitempty"`
+	SeatsTaken  uint      `json:"seatsTaken"`
+	SeatsOpen   uint      `json:"seatsOpen"`
+	Status      Flags     `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastActive  time.Time `json:"lastActive"`
+}
+
+type Lobby struct {
+	Games    []GameSummary `json:"games"`
+	Total    int           `json:"total"`
+	Page     uint          `json:"page"`
+	PageSize uint          `json:"pageSize"`
+}
@@ -36,9 +36,7 @@ func (t *Timer) Pause() {
 		return
 	}
 
-	if !t.timer.Stop() {
-		<-t.timer.C
-	}
+	t.timer.Stop()
 
 	// Add bonus time when pausing
 	elapsed := (time.Since(t.last) - t.bonus)
@@ -52,11 +50,14 @@ func (t *Timer) Start() {
 	defer t.mtx.Unlock()
 
 	t.last = time.Now()
-	t.timer = time.NewTimer(t.remaining)
-
-	go func() {
-		<-t.timer.C // Wait for the timer to expire
+	var timer *time.Timer
+	timer = time.AfterFunc(t.remaining, func() {
 		t.mtx.Lock()
+		if t.timer != timer {
+			// paused or reset just as it fired
+			t.mtx.Unlock()
+			return
+		}
 		t.remaining = 0
 		t.expired = true
 		t.timer = nil
@@ -64,7 +65,22 @@ func (t *Timer) Start() {
 		if t.callback != nil {
 			t.callback(t.callbackArgs...)
 		}
-	}()
+	})
+	t.timer = timer
+}
+
+// Stop the timer without adding bonus time, and set the time remaining
+func (t *Timer) Reset(ms uint) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.remaining = time.Duration(ms) * time.Millisecond
+	t.last = time.Now()
+	t.expired = false
 }
 
 func (t *Timer) TimeLeftMs() uint {
@@ -0,0 +1,66 @@
+package utilities
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimerExpires(t *testing.T) {
+	fired := make(chan struct{}, 1)
+	timer := InitTimer(10, 0, func(args ...any) { fired <- struct{}{} })
+	timer.Start()
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer never fired")
+	}
+	if left := timer.TimeLeftMs(); left != 0 {
+		t.Errorf("expired timer has %dms left", left)
+	}
+}
+
+func TestTimerPauseStopsCallback(t *testing.T) {
+	var fired atomic.Int32
+	timer := InitTimer(20, 0, func(args ...any) { fired.Add(1) })
+	timer.Start()
+	timer.Pause()
+
+	time.Sleep(50 * time.Millisecond)
+	if fired.Load() != 0 {
+		t.Error("paused timer fired")
+	}
+	if timer.TimeLeftMs() == 0 {
+		t.Error("paused timer lost its remaining time")
+	}
+}
+
+// Resetting or pausing as the timer fires must neither block nor run the callback late
+func TestTimerResetRacingExpiry(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		var fired atomic.Int32
+		timer := InitTimer(1, 0, func(args ...any) { fired.Add(1) })
+		timer.Start()
+		time.Sleep(time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			timer.Pause()
+			timer.Reset(1000)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("reset blocked on a timer that had just fired")
+		}
+
+		if fired.Load() > 1 {
+			t.Fatalf("callback ran %d times", fired.Load())
+		}
+		if left := timer.TimeLeftMs(); left != 1000 {
+			t.Fatalf("reset timer has %dms left, want 1000", left)
+		}
+	}
+}